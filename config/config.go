@@ -3,6 +3,9 @@ package config
 import (
 	"log"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
 )
@@ -10,12 +13,68 @@ import (
 type Config struct {
 	TelegramBotToken string
 	TelegramChatID   string
+	DBDriver         string
+	DatabasePath     string
 	MySQLHost        string
 	MySQLPort        string
 	MySQLUser        string
 	MySQLPassword    string
 	MySQLDatabase    string
 	LogLevel         string
+	SyncTimeout      time.Duration // deadline for one fetch→process→write cycle
+
+	EnabledExchanges  []string // empty means every registered exchange
+	DisabledExchanges []string
+
+	DaemonInterval      time.Duration
+	DaemonJitter        time.Duration
+	ShutdownGracePeriod time.Duration
+
+	// NotificationRetention is how long sent and failed outbox messages are
+	// kept; 0 keeps them forever.
+	NotificationRetention time.Duration
+
+	// DelistGuardThreshold is the largest fraction of a market's stored
+	// symbols that may disappear in one poll before delistings are held back.
+	DelistGuardThreshold  float64
+	DelistGuardThresholds map[string]float64 // per-exchange overrides
+	// Markets below DelistGuardMinCount stored symbols are only held back
+	// when the exchange returns an empty list, and a drop is applied once it
+	// persists for DelistGuardConfirmAfter.
+	DelistGuardMinCount     int
+	DelistGuardConfirmAfter time.Duration
+
+	// Optional notification sinks next to Telegram; each one is enabled by
+	// its URL.
+	DiscordWebhookURL string
+	SlackWebhookURL   string
+	WebhookURL        string
+	WebhookSecret     string // HMAC-SHA256 signing key for WebhookURL
+
+	// Chat robots of Feishu/Lark, DingTalk and WeCom. The secrets enable the
+	// robots' signature check; DingTalkKeyword is added to every message for
+	// robots secured by a custom keyword.
+	FeishuWebhookURL   string
+	FeishuSecret       string
+	DingTalkWebhookURL string
+	DingTalkSecret     string
+	DingTalkKeyword    string
+	WeComWebhookURL    string
+
+	// SMTP email notifications, enabled by SMTPHost. EmailToExchanges sends
+	// an exchange's symbols to its own recipients instead of EmailTo. With
+	// EmailDigest set to "daily" all mails of a day are merged into one sent
+	// at EmailDigestTime (local time of day).
+	SMTPHost         string
+	SMTPPort         string
+	SMTPUsername     string
+	SMTPPassword     string
+	SMTPFrom         string
+	SMTPSecurity     string // starttls, tls or none; derived from the port if empty
+	EmailTo          []string
+	EmailToExchanges map[string][]string
+	EmailDigest      string
+	EmailDigestTime  time.Duration
 }
 
 func Load() *Config {
@@ -27,12 +86,53 @@ func Load() *Config {
 	return &Config{
 		TelegramBotToken: getEnv("TELEGRAM_BOT_TOKEN", ""),
 		TelegramChatID:   getEnv("TELEGRAM_CHAT_ID", ""),
+		DBDriver:         getEnv("DB_DRIVER", "mysql"),
+		DatabasePath:     getEnv("DATABASE_PATH", "symbols.db"),
 		MySQLHost:        getEnv("MYSQL_HOST", "localhost"),
 		MySQLPort:        getEnv("MYSQL_PORT", "3306"),
 		MySQLUser:        getEnv("MYSQL_USER", "root"),
 		MySQLPassword:    getEnv("MYSQL_PASSWORD", ""),
 		MySQLDatabase:    getEnv("MYSQL_DATABASE", "exchange_symbols"),
 		LogLevel:         getEnv("LOG_LEVEL", "info"),
+		SyncTimeout:      getEnvDuration("SYNC_TIMEOUT", 2*time.Minute),
+
+		EnabledExchanges:  getEnvList("ENABLED_EXCHANGES"),
+		DisabledExchanges: getEnvList("DISABLED_EXCHANGES"),
+
+		DaemonInterval:      getEnvDuration("DAEMON_INTERVAL", 5*time.Second),
+		DaemonJitter:        getEnvDuration("DAEMON_JITTER", time.Second),
+		ShutdownGracePeriod: getEnvDuration("SHUTDOWN_GRACE_PERIOD", 30*time.Second),
+
+		NotificationRetention: getEnvDuration("NOTIFICATION_RETENTION", 7*24*time.Hour),
+
+		DelistGuardThreshold:  getEnvFloat("DELIST_GUARD_THRESHOLD", 0.2),
+		DelistGuardThresholds: getEnvFloatMap("DELIST_GUARD_THRESHOLDS"),
+
+		DelistGuardMinCount:     getEnvInt("DELIST_GUARD_MIN_COUNT", 20),
+		DelistGuardConfirmAfter: getEnvDuration("DELIST_GUARD_CONFIRM_AFTER", time.Hour),
+
+		DiscordWebhookURL: getEnv("DISCORD_WEBHOOK_URL", ""),
+		SlackWebhookURL:   getEnv("SLACK_WEBHOOK_URL", ""),
+		WebhookURL:        getEnv("WEBHOOK_URL", ""),
+		WebhookSecret:     getEnv("WEBHOOK_SECRET", ""),
+
+		FeishuWebhookURL:   getEnv("FEISHU_WEBHOOK_URL", ""),
+		FeishuSecret:       getEnv("FEISHU_SECRET", ""),
+		DingTalkWebhookURL: getEnv("DINGTALK_WEBHOOK_URL", ""),
+		DingTalkSecret:     getEnv("DINGTALK_SECRET", ""),
+		DingTalkKeyword:    getEnv("DINGTALK_KEYWORD", ""),
+		WeComWebhookURL:    getEnv("WECOM_WEBHOOK_URL", ""),
+
+		SMTPHost:         getEnv("SMTP_HOST", ""),
+		SMTPPort:         getEnv("SMTP_PORT", "587"),
+		SMTPUsername:     getEnv("SMTP_USERNAME", ""),
+		SMTPPassword:     getEnv("SMTP_PASSWORD", ""),
+		SMTPFrom:         getEnv("SMTP_FROM", ""),
+		SMTPSecurity:     strings.ToLower(getEnv("SMTP_SECURITY", "")),
+		EmailTo:          getEnvList("EMAIL_TO"),
+		EmailToExchanges: getEnvListMap("EMAIL_TO_EXCHANGES"),
+		EmailDigest:      strings.ToLower(getEnv("EMAIL_DIGEST", "")),
+		EmailDigestTime:  getEnvTimeOfDay("EMAIL_DIGEST_TIME", 9*time.Hour),
 	}
 }
 
@@ -43,3 +143,117 @@ func getEnv(key, defaultValue string) string {
 	}
 	return value
 }
+
+// getEnvList parses a comma separated, lower-cased list.
+func getEnvList(key string) []string {
+	var result []string
+	for _, item := range strings.Split(os.Getenv(key), ",") {
+		item = strings.ToLower(strings.TrimSpace(item))
+		if item != "" {
+			result = append(result, item)
+		}
+	}
+	return result
+}
+
+func getEnvFloat(key string, defaultValue float64) float64 {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	parsed, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		log.Printf("Warning: invalid %s=%q, using default %v", key, value, defaultValue)
+		return defaultValue
+	}
+	return parsed
+}
+
+func getEnvInt(key string, defaultValue int) int {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	parsed, err := strconv.Atoi(strings.TrimSpace(value))
+	if err != nil || parsed < 0 {
+		log.Printf("Warning: invalid %s=%q, using default %v", key, value, defaultValue)
+		return defaultValue
+	}
+	return parsed
+}
+
+func getEnvDuration(key string, defaultValue time.Duration) time.Duration {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	parsed, err := time.ParseDuration(value)
+	if err != nil || parsed < 0 {
+		log.Printf("Warning: invalid %s=%q, using default %v", key, value, defaultValue)
+		return defaultValue
+	}
+	return parsed
+}
+
+// getEnvFloatMap parses "name:value,name:value" pairs, e.g. "gate:0.3,okx:0.1".
+func getEnvFloatMap(key string) map[string]float64 {
+	result := make(map[string]float64)
+	value := os.Getenv(key)
+	if value == "" {
+		return result
+	}
+	for _, pair := range strings.Split(value, ",") {
+		name, raw, ok := strings.Cut(strings.TrimSpace(pair), ":")
+		if !ok {
+			log.Printf("Warning: ignoring malformed %s entry %q", key, pair)
+			continue
+		}
+		parsed, err := strconv.ParseFloat(strings.TrimSpace(raw), 64)
+		if err != nil {
+			log.Printf("Warning: ignoring malformed %s entry %q", key, pair)
+			continue
+		}
+		result[strings.ToLower(strings.TrimSpace(name))] = parsed
+	}
+	return result
+}
+
+// getEnvListMap parses "name:a|b,name:c" pairs into lower-cased lists, e.g.
+// "binance:ops@example.com|desk@example.com,okx:okx@example.com".
+func getEnvListMap(key string) map[string][]string {
+	result := make(map[string][]string)
+	value := os.Getenv(key)
+	if value == "" {
+		return result
+	}
+	for _, pair := range strings.Split(value, ",") {
+		name, raw, ok := strings.Cut(strings.TrimSpace(pair), ":")
+		name = strings.ToLower(strings.TrimSpace(name))
+		if !ok || name == "" {
+			log.Printf("Warning: ignoring malformed %s entry %q", key, pair)
+			continue
+		}
+		for _, item := range strings.Split(raw, "|") {
+			item = strings.ToLower(strings.TrimSpace(item))
+			if item != "" {
+				result[name] = append(result[name], item)
+			}
+		}
+	}
+	return result
+}
+
+// getEnvTimeOfDay parses a "15:04" local time of day into the duration since
+// midnight.
+func getEnvTimeOfDay(key string, defaultValue time.Duration) time.Duration {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	parsed, err := time.Parse("15:04", strings.TrimSpace(value))
+	if err != nil {
+		log.Printf("Warning: invalid %s=%q, using default %v", key, value, defaultValue)
+		return defaultValue
+	}
+	return time.Duration(parsed.Hour())*time.Hour + time.Duration(parsed.Minute())*time.Minute
+}
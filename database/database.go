@@ -1,50 +1,91 @@
 package database
 
 import (
+	"all_exchange_symbol/config"
 	"all_exchange_symbol/models"
 	"fmt"
 	"log"
 	"os"
+	"path/filepath"
+	"strings"
 
+	"github.com/glebarez/sqlite"
 	"gorm.io/driver/mysql"
 	"gorm.io/gorm"
 )
 
+const (
+	DriverMySQL  = "mysql"
+	DriverSQLite = "sqlite"
+)
+
 var DB *gorm.DB
 
-func Initialize() {
+// migratedModels are the tables AutoMigrate creates and updates.
+var migratedModels = []interface{}{&models.Symbol{}, &models.MarketFetchResult{}, &models.Notification{}}
+
+func Initialize(cfg *config.Config) {
 	var err error
 
-	// Get MySQL connection parameters from environment variables
-	host := getEnv("MYSQL_HOST", "localhost")
-	port := getEnv("MYSQL_PORT", "3306")
-	user := getEnv("MYSQL_USER", "root")
-	password := getEnv("MYSQL_PASSWORD", "")
-	dbname := getEnv("MYSQL_DATABASE", "exchange_symbols")
+	driver := strings.ToLower(cfg.DBDriver)
 
-	// Create DSN (Data Source Name)
-	dsn := fmt.Sprintf("%s:%s@tcp(%s:%s)/%s?charset=utf8mb4&parseTime=True&loc=Local",
-		user, password, host, port, dbname)
+	dialector, err := openDialector(cfg, driver)
+	if err != nil {
+		log.Fatal("Failed to configure database:", err)
+	}
 
-	DB, err = gorm.Open(mysql.Open(dsn), &gorm.Config{})
+	DB, err = gorm.Open(dialector, &gorm.Config{})
 	if err != nil {
-		log.Fatal("Failed to connect to MySQL database:", err)
+		log.Fatalf("Failed to connect to %s database: %v", driver, err)
+	}
+
+	if driver == DriverSQLite {
+		// SQLite只允许一个写连接，避免并发写入时出现 "database is locked"
+		sqlDB, err := DB.DB()
+		if err != nil {
+			log.Fatal("Failed to get SQLite database instance:", err)
+		}
+		sqlDB.SetMaxOpenConns(1)
 	}
 
-	err = DB.AutoMigrate(&models.Symbol{})
+	err = DB.AutoMigrate(migratedModels...)
 	if err != nil {
 		log.Fatal("Failed to migrate database:", err)
 	}
 
-	log.Println("MySQL database initialized successfully")
+	log.Printf("%s database initialized successfully", driverDisplayName(driver))
 }
 
-func getEnv(key, defaultValue string) string {
-	value := os.Getenv(key)
-	if value == "" {
-		return defaultValue
+func openDialector(cfg *config.Config, driver string) (gorm.Dialector, error) {
+	switch driver {
+	case DriverMySQL:
+		// Create DSN (Data Source Name)
+		dsn := fmt.Sprintf("%s:%s@tcp(%s:%s)/%s?charset=utf8mb4&parseTime=True&loc=Local",
+			cfg.MySQLUser, cfg.MySQLPassword, cfg.MySQLHost, cfg.MySQLPort, cfg.MySQLDatabase)
+
+		return mysql.Open(dsn), nil
+	case DriverSQLite:
+		path := cfg.DatabasePath
+		if path != ":memory:" {
+			if dir := filepath.Dir(path); dir != "." {
+				if err := os.MkdirAll(dir, 0o755); err != nil {
+					return nil, fmt.Errorf("create database directory %s: %v", dir, err)
+				}
+			}
+		}
+
+		dsn := path + "?_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)&_pragma=foreign_keys(1)"
+		return sqlite.Open(dsn), nil
+	default:
+		return nil, fmt.Errorf("unsupported DB_DRIVER %q (expected %s or %s)", driver, DriverMySQL, DriverSQLite)
 	}
-	return value
+}
+
+func driverDisplayName(driver string) string {
+	if driver == DriverSQLite {
+		return "SQLite"
+	}
+	return "MySQL"
 }
 
 func Close() {
@@ -53,5 +94,9 @@ func Close() {
 		log.Println("Error getting database instance:", err)
 		return
 	}
-	sqlDB.Close()
+	if err := sqlDB.Close(); err != nil {
+		log.Println("Error closing database:", err)
+		return
+	}
+	log.Println("Database connection closed")
 }
@@ -0,0 +1,39 @@
+package database
+
+import (
+	"strings"
+	"sync"
+	"testing"
+
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm/schema"
+)
+
+// TestMySQLIndexedColumnsHaveLength checks that every indexed column maps to
+// a sized type on MySQL, which cannot index TEXT columns without a prefix
+// length (error 1170). The tests run on SQLite, which accepts either.
+func TestMySQLIndexedColumnsHaveLength(t *testing.T) {
+	// Initialize is never called, so the datetime precision it defaults to
+	// has to be given here
+	precision := 3
+	dialector := mysql.New(mysql.Config{
+		SkipInitializeWithVersion: true,
+		DefaultDatetimePrecision:  &precision,
+	}).(*mysql.Dialector)
+
+	for _, model := range migratedModels {
+		s, err := schema.Parse(model, &sync.Map{}, schema.NamingStrategy{})
+		if err != nil {
+			t.Fatalf("parse %T: %v", model, err)
+		}
+
+		for _, index := range s.ParseIndexes() {
+			for _, option := range index.Fields {
+				dataType := strings.ToLower(dialector.DataTypeOf(option.Field))
+				if strings.Contains(dataType, "text") || strings.Contains(dataType, "blob") {
+					t.Errorf("%s.%s in index %s is %s on MySQL", s.Table, option.DBName, index.Name, dataType)
+				}
+			}
+		}
+	}
+}
@@ -2,59 +2,122 @@ package exchanges
 
 import (
 	"all_exchange_symbol/models"
-	"encoding/json"
-	"io"
+	"context"
 	"log"
-	"net/http"
+	"strconv"
 	"time"
 )
 
 type Binance struct {
-	Name string
+	Name   string
+	client *HTTPClient
+}
+
+type BinanceFilter struct {
+	FilterType  string `json:"filterType"`
+	TickSize    string `json:"tickSize"`
+	StepSize    string `json:"stepSize"`
+	MinQty      string `json:"minQty"`
+	MinNotional string `json:"minNotional"`
+	Notional    string `json:"notional"`
 }
 
 type BinanceSpotSymbol struct {
-	Symbol string `json:"symbol"`
-	Status string `json:"status"`
+	Symbol     string          `json:"symbol"`
+	Status     string          `json:"status"`
+	BaseAsset  string          `json:"baseAsset"`
+	QuoteAsset string          `json:"quoteAsset"`
+	Filters    []BinanceFilter `json:"filters"`
 }
 
+// BinanceFuturesSymbol covers both USDⓈ-M (fapi) and COIN-M (dapi)
+// contracts. COIN-M reports its status as contractStatus and has a
+// contractSize in USD; USDⓈ-M contracts always have a size of one coin.
 type BinanceFuturesSymbol struct {
-	Symbol string `json:"symbol"`
-	Status string `json:"status"`
+	Symbol            string          `json:"symbol"`
+	Status            string          `json:"status"`
+	ContractStatus    string          `json:"contractStatus"`
+	BaseAsset         string          `json:"baseAsset"`
+	QuoteAsset        string          `json:"quoteAsset"`
+	MarginAsset       string          `json:"marginAsset"`
+	ContractType      string          `json:"contractType"`
+	ContractSize      int             `json:"contractSize"`
+	DeliveryDate      int64           `json:"deliveryDate"`
+	OnboardDate       int64           `json:"onboardDate"`
+	PricePrecision    int             `json:"pricePrecision"`
+	QuantityPrecision int             `json:"quantityPrecision"`
+	Filters           []BinanceFilter `json:"filters"`
+}
+
+var binanceMarkets = []models.MarketType{
+	models.MarketSpot,
+	models.MarketLinearPerpetual,
+	models.MarketLinearDelivery,
+	models.MarketInversePerpetual,
+	models.MarketInverseDelivery,
+}
+
+func init() {
+	Register(Registration{
+		Name:       "binance",
+		Markets:    binanceMarkets,
+		HTTPConfig: binanceHTTPConfig(),
+		New: func(config HTTPConfig) ExchangeInterface {
+			return newBinanceWithConfig(config)
+		},
+	})
+}
+
+func binanceHTTPConfig() HTTPConfig {
+	config := DefaultHTTPConfig()
+	// spot exchangeInfo is several MB, give it more time than the default
+	config.Timeout = 30 * time.Second
+	config.RequestsPerSecond = 2
+	config.Burst = 2
+
+	return config
 }
 
 func NewBinance() *Binance {
-	return &Binance{Name: "binance"}
+	return newBinanceWithConfig(binanceHTTPConfig())
+}
+
+func newBinanceWithConfig(config HTTPConfig) *Binance {
+	return &Binance{
+		Name:   "binance",
+		client: NewHTTPClient("binance", config),
+	}
 }
 
 func (b *Binance) GetName() string {
 	return b.Name
 }
 
-func (b *Binance) FetchSpotSymbols() ([]models.Symbol, error) {
-	log.Printf("开始获取币安现货交易对数据...")
+func (b *Binance) SupportedMarkets() []models.MarketType {
+	return binanceMarkets
+}
 
-	resp, err := http.Get("https://api.binance.com/api/v3/exchangeInfo")
-	if err != nil {
-		log.Printf("币安现货API请求失败: %v", err)
-		return nil, err
+func (b *Binance) FetchSymbols(ctx context.Context, market models.MarketType) ([]models.Symbol, error) {
+	switch market {
+	case models.MarketSpot:
+		return b.fetchSpotSymbols(ctx)
+	case models.MarketLinearPerpetual, models.MarketLinearDelivery,
+		models.MarketInversePerpetual, models.MarketInverseDelivery:
+		return b.fetchFuturesSymbols(ctx, market)
+	default:
+		return nil, unsupportedMarket(b.Name, market)
 	}
-	defer resp.Body.Close()
-
-	log.Printf("币安现货API请求成功，状态码: %d", resp.StatusCode)
+}
 
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		log.Printf("读取币安现货API响应失败: %v", err)
-		return nil, err
-	}
+func (b *Binance) fetchSpotSymbols(ctx context.Context) ([]models.Symbol, error) {
+	log.Printf("开始获取币安现货交易对数据...")
 
 	var result struct {
 		Symbols []BinanceSpotSymbol `json:"symbols"`
 	}
 
-	if err := json.Unmarshal(body, &result); err != nil {
-		log.Printf("解析币安现货API响应失败: %v", err)
+	if err := b.client.GetJSON(ctx, "spot", "https://api.binance.com/api/v3/exchangeInfo", &result); err != nil {
+		log.Printf("币安现货API请求失败: %v", err)
 		return nil, err
 	}
 
@@ -63,11 +126,21 @@ func (b *Binance) FetchSpotSymbols() ([]models.Symbol, error) {
 	var symbols []models.Symbol
 
 	for _, s := range result.Symbols {
+		info := models.InstrumentInfo{
+			BaseAsset:      s.BaseAsset,
+			QuoteAsset:     s.QuoteAsset,
+			ExchangeStatus: s.Status,
+		}
+		applyBinanceFilters(&info, s.Filters)
+		info.PricePrecision = decimalPlaces(info.TickSize)
+		info.QuantityPrecision = decimalPlaces(info.LotSize)
+
 		symbols = append(symbols, models.Symbol{
-			Exchange:  b.Name,
-			Type:      "spot",
-			Symbol:    s.Symbol,
-			CreatedAt: time.Now(),
+			Exchange:       b.Name,
+			Type:           models.MarketSpot,
+			Symbol:         s.Symbol,
+			InstrumentInfo: info,
+			CreatedAt:      time.Now(),
 		})
 	}
 
@@ -75,46 +148,114 @@ func (b *Binance) FetchSpotSymbols() ([]models.Symbol, error) {
 	return symbols, nil
 }
 
-func (b *Binance) FetchFuturesSymbols() ([]models.Symbol, error) {
-	log.Printf("开始获取币安合约交易对数据...")
-
-	resp, err := http.Get("https://fapi.binance.com/fapi/v1/exchangeInfo")
-	if err != nil {
-		log.Printf("币安合约API请求失败: %v", err)
-		return nil, err
+func (b *Binance) fetchFuturesSymbols(ctx context.Context, market models.MarketType) ([]models.Symbol, error) {
+	// USDⓈ-M合约在fapi，币本位合约在dapi
+	url, label := "https://fapi.binance.com/fapi/v1/exchangeInfo", "U本位合约"
+	if market.IsInverse() {
+		url, label = "https://dapi.binance.com/dapi/v1/exchangeInfo", "币本位合约"
 	}
-	defer resp.Body.Close()
-
-	log.Printf("币安合约API请求成功，状态码: %d", resp.StatusCode)
 
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		log.Printf("读取币安合约API响应失败: %v", err)
-		return nil, err
-	}
+	log.Printf("开始获取币安%s交易对数据...", label)
 
 	var result struct {
 		Symbols []BinanceFuturesSymbol `json:"symbols"`
 	}
 
-	if err := json.Unmarshal(body, &result); err != nil {
-		log.Printf("解析币安合约API响应失败: %v", err)
+	if err := b.client.GetJSON(ctx, string(market), url, &result); err != nil {
+		log.Printf("币安%sAPI请求失败: %v", label, err)
 		return nil, err
 	}
 
-	log.Printf("从币安API获取到 %d 个合约交易对", len(result.Symbols))
+	log.Printf("从币安API获取到 %d 个%s交易对", len(result.Symbols), label)
 
 	var symbols []models.Symbol
 
 	for _, s := range result.Symbols {
-		symbols = append(symbols, models.Symbol{
-			Exchange:  b.Name,
-			Type:      "futures",
-			Symbol:    s.Symbol,
-			CreatedAt: time.Now(),
-		})
+		if s.marketType(market.IsInverse()) != market {
+			continue
+		}
+
+		info := models.InstrumentInfo{
+			BaseAsset:         s.BaseAsset,
+			QuoteAsset:        s.QuoteAsset,
+			SettleAsset:       s.MarginAsset,
+			ExchangeStatus:    firstNonEmpty(s.Status, s.ContractStatus),
+			PricePrecision:    s.PricePrecision,
+			QuantityPrecision: s.QuantityPrecision,
+			ContractSize:      "1",
+			ContractType:      s.ContractType,
+		}
+		if s.ContractSize > 0 {
+			info.ContractSize = strconv.Itoa(s.ContractSize)
+		}
+		applyBinanceFilters(&info, s.Filters)
+
+		symbol := models.Symbol{
+			Exchange:       b.Name,
+			Type:           market,
+			Symbol:         s.Symbol,
+			InstrumentInfo: info,
+			ListedAt:       unixMillis(s.OnboardDate),
+			CreatedAt:      time.Now(),
+		}
+		// 永续合约的deliveryDate是2100年的占位值，只记录交割合约的交割时间
+		if market.IsDelivery() && s.DeliveryDate > 0 && s.DeliveryDate < binancePerpetualDeliveryDate {
+			deliveryAt := time.UnixMilli(s.DeliveryDate)
+			symbol.DeliveryAt = &deliveryAt
+		}
+		symbols = append(symbols, symbol)
 	}
 
-	log.Printf("币安合约交易对处理完成 - 共 %d 个", len(symbols))
+	log.Printf("币安%s %s交易对处理完成 - 共 %d 个", label, market.Label(), len(symbols))
 	return symbols, nil
 }
+
+// binancePerpetualDeliveryDate is the placeholder deliveryDate (2100-12-25)
+// Binance reports for perpetual contracts.
+const binancePerpetualDeliveryDate = 4133404800000
+
+// marketType classifies a contract by its contractType. Settled contracts
+// report an empty contractType, so their deliveryDate decides instead.
+func (s BinanceFuturesSymbol) marketType(coinMargined bool) models.MarketType {
+	delivery := false
+	switch s.ContractType {
+	case "CURRENT_QUARTER", "NEXT_QUARTER", "CURRENT_MONTH", "NEXT_MONTH":
+		delivery = true
+	case "":
+		delivery = s.DeliveryDate > 0 && s.DeliveryDate < binancePerpetualDeliveryDate
+	}
+
+	switch {
+	case coinMargined && delivery:
+		return models.MarketInverseDelivery
+	case coinMargined:
+		return models.MarketInversePerpetual
+	case delivery:
+		return models.MarketLinearDelivery
+	default:
+		return models.MarketLinearPerpetual
+	}
+}
+
+// applyBinanceFilters copies tick size, lot size and minimums from the
+// PRICE_FILTER, LOT_SIZE and (MIN_)NOTIONAL filters.
+func applyBinanceFilters(info *models.InstrumentInfo, filters []BinanceFilter) {
+	for _, f := range filters {
+		switch f.FilterType {
+		case "PRICE_FILTER":
+			info.TickSize = f.TickSize
+		case "LOT_SIZE":
+			info.LotSize = f.StepSize
+			info.MinQty = f.MinQty
+		case "MIN_NOTIONAL":
+			// spot uses minNotional, USDⓈ-M futures use notional
+			if f.MinNotional != "" {
+				info.MinNotional = f.MinNotional
+			} else {
+				info.MinNotional = f.Notional
+			}
+		case "NOTIONAL":
+			info.MinNotional = f.MinNotional
+		}
+	}
+}
@@ -2,14 +2,16 @@ package exchanges
 
 import (
 	"all_exchange_symbol/models"
-	"encoding/json"
-	"io"
-	"net/http"
+	"context"
+	"math"
+	"strconv"
+	"strings"
 	"time"
 )
 
 type Bitget struct {
-	Name string
+	Name   string
+	client *HTTPClient
 }
 
 type BitgetSymbol struct {
@@ -22,95 +24,268 @@ type BitgetSymbol struct {
 	MakerFeeRate      string `json:"makerFeeRate"`
 	PricePrecision    string `json:"pricePrecision"`
 	QuantityPrecision string `json:"quantityPrecision"`
+	MinTradeUSDT      string `json:"minTradeUSDT"`
 	Status            string `json:"status"`
 }
 
 type BitgetFuturesSymbol struct {
-	Symbol              string `json:"symbol"`
-	BaseCoin            string `json:"baseCoin"`
-	QuoteCoin           string `json:"quoteCoin"`
-	BuyLimitPriceRatio  string `json:"buyLimitPriceRatio"`
-	SellLimitPriceRatio string `json:"sellLimitPriceRatio"`
-	FeeRateUpRatio      string `json:"feeRateUpRatio"`
-	MakerFeeRate        string `json:"makerFeeRate"`
-	TakerFeeRate        string `json:"takerFeeRate"`
-	Status              string `json:"status"`
+	Symbol              string   `json:"symbol"`
+	BaseCoin            string   `json:"baseCoin"`
+	QuoteCoin           string   `json:"quoteCoin"`
+	BuyLimitPriceRatio  string   `json:"buyLimitPriceRatio"`
+	SellLimitPriceRatio string   `json:"sellLimitPriceRatio"`
+	FeeRateUpRatio      string   `json:"feeRateUpRatio"`
+	MakerFeeRate        string   `json:"makerFeeRate"`
+	TakerFeeRate        string   `json:"takerFeeRate"`
+	SymbolType          string   `json:"symbolType"`
+	SymbolStatus        string   `json:"symbolStatus"`
+	SupportMarginCoins  []string `json:"supportMarginCoins"`
+	MinTradeNum         string   `json:"minTradeNum"`
+	MinTradeUSDT        string   `json:"minTradeUSDT"`
+	PriceEndStep        string   `json:"priceEndStep"`
+	PricePlace          string   `json:"pricePlace"`
+	VolumePlace         string   `json:"volumePlace"`
+	SizeMultiplier      string   `json:"sizeMultiplier"`
+	LaunchTime          string   `json:"launchTime"`
+	DeliveryTime        string   `json:"deliveryTime"`
+}
+
+// Bitget v2 product types. COIN-FUTURES holds the coin-margined perpetual
+// and delivery contracts, the other two are margined in the quote coin.
+const (
+	bitgetUSDTFutures = "USDT-FUTURES"
+	bitgetCoinFutures = "COIN-FUTURES"
+	bitgetUSDCFutures = "USDC-FUTURES"
+)
+
+var bitgetMarkets = []models.MarketType{
+	models.MarketSpot,
+	models.MarketLinearPerpetual,
+	models.MarketLinearDelivery,
+	models.MarketInversePerpetual,
+	models.MarketInverseDelivery,
+}
+
+// bitgetV1Suffixes are the product suffixes of v1 symbol names, e.g.
+// BTCUSDT_UMCBL, which v2 dropped.
+var bitgetV1Suffixes = []string{"_SPBL", "_UMCBL", "_DMCBL", "_CMCBL"}
+
+func init() {
+	Register(Registration{
+		Name:       "bitget",
+		Markets:    bitgetMarkets,
+		HTTPConfig: bitgetHTTPConfig(),
+		New: func(config HTTPConfig) ExchangeInterface {
+			return newBitgetWithConfig(config)
+		},
+	})
+}
+
+func bitgetHTTPConfig() HTTPConfig {
+	config := DefaultHTTPConfig()
+	// public market endpoints allow 20 requests per second
+	config.RequestsPerSecond = 10
+	config.Burst = 10
+
+	return config
 }
 
 func NewBitget() *Bitget {
-	return &Bitget{Name: "bitget"}
+	return newBitgetWithConfig(bitgetHTTPConfig())
+}
+
+func newBitgetWithConfig(config HTTPConfig) *Bitget {
+	return &Bitget{
+		Name:   "bitget",
+		client: NewHTTPClient("bitget", config),
+	}
 }
 
 func (b *Bitget) GetName() string {
 	return b.Name
 }
 
-func (b *Bitget) FetchSpotSymbols() ([]models.Symbol, error) {
-	resp, err := http.Get("https://api.bitget.com/api/spot/v1/public/products")
-	if err != nil {
-		return nil, err
-	}
-	defer resp.Body.Close()
+func (b *Bitget) SupportedMarkets() []models.MarketType {
+	return bitgetMarkets
+}
 
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, err
+func (b *Bitget) FetchSymbols(ctx context.Context, market models.MarketType) ([]models.Symbol, error) {
+	switch market {
+	case models.MarketSpot:
+		return b.fetchSpotSymbols(ctx)
+	case models.MarketLinearPerpetual, models.MarketLinearDelivery:
+		return b.fetchFuturesSymbols(ctx, market, bitgetUSDTFutures, bitgetUSDCFutures)
+	case models.MarketInversePerpetual, models.MarketInverseDelivery:
+		return b.fetchFuturesSymbols(ctx, market, bitgetCoinFutures)
+	default:
+		return nil, unsupportedMarket(b.Name, market)
 	}
+}
 
+func (b *Bitget) fetchSpotSymbols(ctx context.Context) ([]models.Symbol, error) {
 	var result struct {
 		Code string         `json:"code"`
 		Msg  string         `json:"msg"`
 		Data []BitgetSymbol `json:"data"`
 	}
 
-	if err := json.Unmarshal(body, &result); err != nil {
+	if err := b.client.GetJSON(ctx, "spot", "https://api.bitget.com/api/v2/spot/public/symbols", &result); err != nil {
+		return nil, err
+	}
+
+	if err := checkBitgetCode(result.Code, result.Msg, "spot"); err != nil {
 		return nil, err
 	}
 
 	var symbols []models.Symbol
 	for _, s := range result.Data {
 		symbols = append(symbols, models.Symbol{
-			Exchange:  b.Name,
-			Type:      "spot",
-			Symbol:    s.Symbol,
-			CreatedAt: time.Now(),
+			Exchange:       b.Name,
+			Type:           models.MarketSpot,
+			Symbol:         s.Symbol,
+			InstrumentInfo: s.instrumentInfo(),
+			CreatedAt:      time.Now(),
 		})
 	}
 
 	return symbols, nil
 }
 
-func (b *Bitget) FetchFuturesSymbols() ([]models.Symbol, error) {
-	resp, err := http.Get("https://api.bitget.com/api/mix/v1/market/contracts?productType=umcbl")
-	if err != nil {
-		return nil, err
+func (b *Bitget) fetchFuturesSymbols(ctx context.Context, market models.MarketType, productTypes ...string) ([]models.Symbol, error) {
+	var symbols []models.Symbol
+
+	for _, productType := range productTypes {
+		var result struct {
+			Code string                `json:"code"`
+			Msg  string                `json:"msg"`
+			Data []BitgetFuturesSymbol `json:"data"`
+		}
+
+		endpoint := "https://api.bitget.com/api/v2/mix/market/contracts?productType=" + productType
+		if err := b.client.GetJSON(ctx, string(market), endpoint, &result); err != nil {
+			return nil, err
+		}
+
+		if err := checkBitgetCode(result.Code, result.Msg, string(market)); err != nil {
+			return nil, err
+		}
+
+		for _, s := range result.Data {
+			if s.marketType(productType) != market {
+				continue
+			}
+
+			symbol := models.Symbol{
+				Exchange:       b.Name,
+				Type:           market,
+				Symbol:         s.Symbol,
+				InstrumentInfo: s.instrumentInfo(),
+				ListedAt:       parseMillis(s.LaunchTime),
+				CreatedAt:      time.Now(),
+			}
+			if market.IsInverse() {
+				// coin-margined contracts accept several margin coins but settle in the base coin
+				symbol.SettleAsset = s.BaseCoin
+			}
+			if market.IsDelivery() {
+				symbol.DeliveryAt = parseMillis(s.DeliveryTime)
+			}
+			symbols = append(symbols, symbol)
+		}
 	}
-	defer resp.Body.Close()
 
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, err
+	return symbols, nil
+}
+
+func (s BitgetFuturesSymbol) marketType(productType string) models.MarketType {
+	delivery := s.SymbolType == "delivery"
+	switch {
+	case productType == bitgetCoinFutures && delivery:
+		return models.MarketInverseDelivery
+	case productType == bitgetCoinFutures:
+		return models.MarketInversePerpetual
+	case delivery:
+		return models.MarketLinearDelivery
+	default:
+		return models.MarketLinearPerpetual
 	}
+}
 
-	var result struct {
-		Code string                `json:"code"`
-		Msg  string                `json:"msg"`
-		Data []BitgetFuturesSymbol `json:"data"`
+func (s BitgetSymbol) instrumentInfo() models.InstrumentInfo {
+	pricePrecision := atoiOrZero(s.PricePrecision)
+	quantityPrecision := atoiOrZero(s.QuantityPrecision)
+
+	return models.InstrumentInfo{
+		BaseAsset:         s.BaseCoin,
+		QuoteAsset:        s.QuoteCoin,
+		ExchangeStatus:    s.Status,
+		TickSize:          precisionToStep(pricePrecision),
+		LotSize:           precisionToStep(quantityPrecision),
+		MinQty:            s.MinTradeAmount,
+		MinNotional:       s.MinTradeUSDT,
+		PricePrecision:    pricePrecision,
+		QuantityPrecision: quantityPrecision,
 	}
+}
 
-	if err := json.Unmarshal(body, &result); err != nil {
-		return nil, err
+// instrumentInfo derives the tick size from pricePlace and priceEndStep,
+// e.g. pricePlace 1 and priceEndStep 5 give a tick of 0.5.
+func (s BitgetFuturesSymbol) instrumentInfo() models.InstrumentInfo {
+	pricePlace := atoiOrZero(s.PricePlace)
+	tickSize := precisionToStep(pricePlace)
+	if step, err := strconv.ParseFloat(s.PriceEndStep, 64); err == nil && step > 0 {
+		tickSize = strconv.FormatFloat(step/math.Pow10(pricePlace), 'f', pricePlace, 64)
 	}
 
-	var symbols []models.Symbol
-	for _, s := range result.Data {
-		symbols = append(symbols, models.Symbol{
-			Exchange:  b.Name,
-			Type:      "futures",
-			Symbol:    s.Symbol,
-			CreatedAt: time.Now(),
-		})
+	settle := ""
+	if len(s.SupportMarginCoins) > 0 {
+		settle = s.SupportMarginCoins[0]
 	}
 
-	return symbols, nil
+	return models.InstrumentInfo{
+		BaseAsset:         s.BaseCoin,
+		QuoteAsset:        s.QuoteCoin,
+		SettleAsset:       settle,
+		ExchangeStatus:    s.SymbolStatus,
+		TickSize:          tickSize,
+		LotSize:           s.SizeMultiplier,
+		MinQty:            s.MinTradeNum,
+		MinNotional:       s.MinTradeUSDT,
+		PricePrecision:    pricePlace,
+		QuantityPrecision: atoiOrZero(s.VolumePlace),
+		ContractSize:      "1",
+		ContractType:      s.SymbolType,
+	}
+}
+
+// BitgetV2Symbol maps a v1 symbol name to its v2 name by dropping the
+// product suffix: BTCUSDT_UMCBL -> BTCUSDT. v2 names are returned as is.
+func BitgetV2Symbol(symbol string) string {
+	for _, suffix := range bitgetV1Suffixes {
+		if strings.HasSuffix(symbol, suffix) {
+			return strings.TrimSuffix(symbol, suffix)
+		}
+	}
+	return symbol
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, value := range values {
+		if value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+// checkBitgetCode turns a non-success Bitget business code into an *ExchangeError.
+func checkBitgetCode(code, msg, market string) error {
+	switch code {
+	case "00000":
+		return nil
+	case "429":
+		return newExchangeError(ErrRateLimited, "bitget", market, 0, code, msg)
+	default:
+		return newExchangeError(ErrBadPayload, "bitget", market, 0, code, msg)
+	}
 }
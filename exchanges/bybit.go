@@ -2,113 +2,429 @@ package exchanges
 
 import (
 	"all_exchange_symbol/models"
-	"encoding/json"
-	"io"
-	"net/http"
+	"context"
+	"fmt"
+	"log"
+	"net/url"
+	"sort"
+	"strconv"
+	"sync"
 	"time"
 )
 
 type Bybit struct {
-	Name string
+	Name   string
+	client *HTTPClient
+
+	// mu guards the option base coin discovery; markets are fetched
+	// concurrently and the instance is reused across daemon cycles.
+	mu          sync.Mutex
+	optionCoins map[string]bool // base coins known to have options
+	linearCoins []string        // base coins of the last linear perpetual fetch
+	probeNext   int             // position in linearCoins of the next probe
+}
+
+type BybitPriceFilter struct {
+	TickSize string `json:"tickSize"`
+}
+
+type BybitLotSizeFilter struct {
+	BasePrecision    string `json:"basePrecision"`
+	QtyStep          string `json:"qtyStep"`
+	MinOrderQty      string `json:"minOrderQty"`
+	MinOrderAmt      string `json:"minOrderAmt"`
+	MinNotionalValue string `json:"minNotionalValue"`
 }
 
 type BybitSymbol struct {
-	Symbol    string `json:"symbol"`
-	BaseCoin  string `json:"baseCoin"`
-	QuoteCoin string `json:"quoteCoin"`
-	Status    string `json:"status"`
+	Symbol        string             `json:"symbol"`
+	BaseCoin      string             `json:"baseCoin"`
+	QuoteCoin     string             `json:"quoteCoin"`
+	Status        string             `json:"status"`
+	PriceFilter   BybitPriceFilter   `json:"priceFilter"`
+	LotSizeFilter BybitLotSizeFilter `json:"lotSizeFilter"`
 }
 
 type BybitFuturesSymbol struct {
-	Symbol       string `json:"symbol"`
-	ContractType string `json:"contractType"`
-	Status       string `json:"status"`
-	BaseCoin     string `json:"baseCoin"`
-	QuoteCoin    string `json:"quoteCoin"`
-	LaunchTime   string `json:"launchTime"`
-	DeliveryTime string `json:"deliveryTime"`
+	Symbol        string             `json:"symbol"`
+	ContractType  string             `json:"contractType"`
+	Status        string             `json:"status"`
+	BaseCoin      string             `json:"baseCoin"`
+	QuoteCoin     string             `json:"quoteCoin"`
+	SettleCoin    string             `json:"settleCoin"`
+	LaunchTime    string             `json:"launchTime"`
+	DeliveryTime  string             `json:"deliveryTime"`
+	OptionsType   string             `json:"optionsType"`
+	PriceScale    string             `json:"priceScale"`
+	PriceFilter   BybitPriceFilter   `json:"priceFilter"`
+	LotSizeFilter BybitLotSizeFilter `json:"lotSizeFilter"`
+}
+
+var bybitMarkets = []models.MarketType{
+	models.MarketSpot,
+	models.MarketLinearPerpetual,
+	models.MarketLinearDelivery,
+	models.MarketInversePerpetual,
+	models.MarketInverseDelivery,
+	models.MarketOption,
+}
+
+// bybitOptionBaseCoins are queried one by one because the option category
+// only returns BTC when no baseCoin is given and no endpoint lists the option
+// underlyings. New underlyings are discovered by probing the base coins of
+// the linear perpetuals, bybitOptionProbes per fetch, so a whole rotation
+// takes a few minutes in daemon mode.
+var bybitOptionBaseCoins = []string{"BTC", "ETH", "SOL", "XRP", "DOGE", "MNT"}
+
+const bybitOptionProbes = 5
+
+// bybitPageLimit is the largest page instruments-info accepts.
+const bybitPageLimit = 1000
+
+func init() {
+	Register(Registration{
+		Name:       "bybit",
+		Markets:    bybitMarkets,
+		HTTPConfig: bybitHTTPConfig(),
+		New: func(config HTTPConfig) ExchangeInterface {
+			return newBybitWithConfig(config)
+		},
+	})
+}
+
+func bybitHTTPConfig() HTTPConfig {
+	config := DefaultHTTPConfig()
+	// public market endpoints allow 600 requests per 5 seconds per IP
+	config.RequestsPerSecond = 10
+	config.Burst = 10
+
+	return config
 }
 
 func NewBybit() *Bybit {
-	return &Bybit{Name: "bybit"}
+	return newBybitWithConfig(bybitHTTPConfig())
+}
+
+func newBybitWithConfig(config HTTPConfig) *Bybit {
+	optionCoins := make(map[string]bool)
+	for _, coin := range bybitOptionBaseCoins {
+		optionCoins[coin] = true
+	}
+
+	return &Bybit{
+		Name:        "bybit",
+		client:      NewHTTPClient("bybit", config),
+		optionCoins: optionCoins,
+		// start the rotation at a clock dependent position, so one-shot runs
+		// do not probe the same coins every time
+		probeNext: int(time.Now().Unix() / 60),
+	}
+}
+
+// SeedOptionCoins adds base coins known to have options, e.g. those of the
+// stored option symbols.
+func (b *Bybit) SeedOptionCoins(coins []string) {
+	for _, coin := range coins {
+		if coin != "" {
+			b.addOptionCoin(coin)
+		}
+	}
 }
 
 func (b *Bybit) GetName() string {
 	return b.Name
 }
 
-func (b *Bybit) FetchSpotSymbols() ([]models.Symbol, error) {
-	resp, err := http.Get("https://api.bybit.com/v5/market/instruments-info?category=spot")
+func (b *Bybit) SupportedMarkets() []models.MarketType {
+	return bybitMarkets
+}
+
+func (b *Bybit) FetchSymbols(ctx context.Context, market models.MarketType) ([]models.Symbol, error) {
+	switch market {
+	case models.MarketSpot:
+		return b.fetchSpotSymbols(ctx)
+	case models.MarketLinearPerpetual, models.MarketLinearDelivery:
+		return b.fetchFuturesSymbols(ctx, market, "linear")
+	case models.MarketInversePerpetual, models.MarketInverseDelivery:
+		return b.fetchFuturesSymbols(ctx, market, "inverse")
+	case models.MarketOption:
+		return b.fetchOptionSymbols(ctx)
+	default:
+		return nil, unsupportedMarket(b.Name, market)
+	}
+}
+
+func (b *Bybit) fetchSpotSymbols(ctx context.Context) ([]models.Symbol, error) {
+	list, err := fetchBybitInstruments[BybitSymbol](ctx, b.client, models.MarketSpot, "category=spot")
 	if err != nil {
 		return nil, err
 	}
-	defer resp.Body.Close()
 
-	body, err := io.ReadAll(resp.Body)
+	var symbols []models.Symbol
+	for _, s := range list {
+		symbols = append(symbols, models.Symbol{
+			Exchange: b.Name,
+			Type:     models.MarketSpot,
+			Symbol:   s.Symbol,
+			InstrumentInfo: models.InstrumentInfo{
+				BaseAsset:         s.BaseCoin,
+				QuoteAsset:        s.QuoteCoin,
+				ExchangeStatus:    s.Status,
+				TickSize:          s.PriceFilter.TickSize,
+				LotSize:           s.LotSizeFilter.BasePrecision,
+				MinQty:            s.LotSizeFilter.MinOrderQty,
+				MinNotional:       s.LotSizeFilter.MinOrderAmt,
+				PricePrecision:    decimalPlaces(s.PriceFilter.TickSize),
+				QuantityPrecision: decimalPlaces(s.LotSizeFilter.BasePrecision),
+			},
+			CreatedAt: time.Now(),
+		})
+	}
+
+	return symbols, nil
+}
+
+func (b *Bybit) fetchFuturesSymbols(ctx context.Context, market models.MarketType, category string) ([]models.Symbol, error) {
+	list, err := fetchBybitInstruments[BybitFuturesSymbol](ctx, b.client, market, "category="+category)
 	if err != nil {
 		return nil, err
 	}
 
-	var result struct {
-		RetCode int    `json:"retCode"`
-		RetMsg  string `json:"retMsg"`
-		Result  struct {
-			Category string        `json:"category"`
-			List     []BybitSymbol `json:"list"`
-		} `json:"result"`
+	var symbols []models.Symbol
+	var baseCoins []string
+	for _, s := range list {
+		if s.marketType() != market {
+			continue
+		}
+		symbols = append(symbols, s.symbol(b.Name, market))
+		baseCoins = append(baseCoins, s.BaseCoin)
 	}
 
-	if err := json.Unmarshal(body, &result); err != nil {
-		return nil, err
+	if market == models.MarketLinearPerpetual {
+		b.setLinearCoins(baseCoins)
 	}
 
+	return symbols, nil
+}
+
+func (b *Bybit) fetchOptionSymbols(ctx context.Context) ([]models.Symbol, error) {
 	var symbols []models.Symbol
-	for _, s := range result.Result.List {
-		symbols = append(symbols, models.Symbol{
-			Exchange:  b.Name,
-			Type:      "spot",
-			Symbol:    s.Symbol,
-			CreatedAt: time.Now(),
-		})
+	for _, coin := range b.knownOptionCoins() {
+		list, err := b.fetchOptions(ctx, coin)
+		if err != nil {
+			return nil, err
+		}
+		symbols = append(symbols, list...)
+	}
+
+	// the linear perpetuals are fetched concurrently, so on the first fetch
+	// their base coins may not be known yet
+	if !b.hasLinearCoins() {
+		if _, err := b.fetchFuturesSymbols(ctx, models.MarketLinearPerpetual, "linear"); err != nil {
+			log.Printf("bybit 获取期权探测标的失败: %v", err)
+		}
+	}
+
+	// a failed probe only delays discovery, it must not fail the market
+	for _, coin := range b.nextOptionProbes() {
+		list, err := b.fetchOptions(ctx, coin)
+		if err != nil {
+			log.Printf("bybit 探测 %s 期权失败: %v", coin, err)
+			break
+		}
+		if len(list) > 0 {
+			log.Printf("bybit 发现新的期权标的 %s (%d 个合约)", coin, len(list))
+			b.addOptionCoin(coin)
+			symbols = append(symbols, list...)
+		}
 	}
 
 	return symbols, nil
 }
 
-func (b *Bybit) FetchFuturesSymbols() ([]models.Symbol, error) {
-	resp, err := http.Get("https://api.bybit.com/v5/market/instruments-info?category=linear")
+func (b *Bybit) fetchOptions(ctx context.Context, coin string) ([]models.Symbol, error) {
+	list, err := fetchBybitInstruments[BybitFuturesSymbol](ctx, b.client, models.MarketOption, "category=option&baseCoin="+url.QueryEscape(coin))
 	if err != nil {
 		return nil, err
 	}
-	defer resp.Body.Close()
 
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, err
+	var symbols []models.Symbol
+	for _, s := range list {
+		// without options for coin Bybit may fall back to BTC
+		if s.BaseCoin != "" && s.BaseCoin != coin {
+			continue
+		}
+		symbols = append(symbols, s.symbol(b.Name, models.MarketOption))
 	}
+	return symbols, nil
+}
+
+func (b *Bybit) knownOptionCoins() []string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
 
-	var result struct {
-		RetCode int    `json:"retCode"`
-		RetMsg  string `json:"retMsg"`
-		Result  struct {
-			Category string               `json:"category"`
-			List     []BybitFuturesSymbol `json:"list"`
-		} `json:"result"`
+	coins := make([]string, 0, len(b.optionCoins))
+	for coin := range b.optionCoins {
+		coins = append(coins, coin)
 	}
+	sort.Strings(coins)
+	return coins
+}
 
-	if err := json.Unmarshal(body, &result); err != nil {
-		return nil, err
+func (b *Bybit) addOptionCoin(coin string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.optionCoins[coin] = true
+}
+
+func (b *Bybit) setLinearCoins(baseCoins []string) {
+	seen := make(map[string]bool)
+	var coins []string
+	for _, coin := range baseCoins {
+		if coin != "" && !seen[coin] {
+			seen[coin] = true
+			coins = append(coins, coin)
+		}
 	}
+	sort.Strings(coins)
 
-	var symbols []models.Symbol
-	for _, s := range result.Result.List {
-		symbols = append(symbols, models.Symbol{
-			Exchange:  b.Name,
-			Type:      "futures",
-			Symbol:    s.Symbol,
-			CreatedAt: time.Now(),
-		})
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.linearCoins = coins
+	if len(coins) > 0 {
+		b.probeNext %= len(coins)
 	}
+}
 
-	return symbols, nil
+func (b *Bybit) hasLinearCoins() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return len(b.linearCoins) > 0
+}
+
+// nextOptionProbes returns the next linear perpetual base coins without
+// known options, continuing the rotation where the last fetch stopped.
+func (b *Bybit) nextOptionProbes() []string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	var probes []string
+	for checked := 0; checked < len(b.linearCoins) && len(probes) < bybitOptionProbes; checked++ {
+		coin := b.linearCoins[b.probeNext]
+		b.probeNext = (b.probeNext + 1) % len(b.linearCoins)
+		if !b.optionCoins[coin] {
+			probes = append(probes, coin)
+		}
+	}
+	return probes
+}
+
+// fetchBybitInstruments follows nextPageCursor until the last page of
+// instruments-info. query selects the category (and baseCoin for options).
+func fetchBybitInstruments[T any](ctx context.Context, client *HTTPClient, market models.MarketType, query string) ([]T, error) {
+	var list []T
+	seen := make(map[string]bool)
+	cursor := ""
+
+	for {
+		var result struct {
+			RetCode int    `json:"retCode"`
+			RetMsg  string `json:"retMsg"`
+			Result  struct {
+				Category       string `json:"category"`
+				List           []T    `json:"list"`
+				NextPageCursor string `json:"nextPageCursor"`
+			} `json:"result"`
+		}
+
+		endpoint := fmt.Sprintf("https://api.bybit.com/v5/market/instruments-info?%s&limit=%d", query, bybitPageLimit)
+		if cursor != "" {
+			endpoint += "&cursor=" + url.QueryEscape(cursor)
+		}
+
+		if err := client.GetJSON(ctx, string(market), endpoint, &result); err != nil {
+			return nil, err
+		}
+
+		if err := checkBybitCode(result.RetCode, result.RetMsg, string(market)); err != nil {
+			return nil, err
+		}
+
+		list = append(list, result.Result.List...)
+
+		cursor = result.Result.NextPageCursor
+		if cursor == "" || len(result.Result.List) == 0 {
+			return list, nil
+		}
+		if seen[cursor] {
+			return nil, newExchangeError(ErrBadPayload, "bybit", string(market), 0, "", "nextPageCursor repeated: "+cursor)
+		}
+		seen[cursor] = true
+	}
+}
+
+func (s BybitFuturesSymbol) symbol(exchange string, market models.MarketType) models.Symbol {
+	symbol := models.Symbol{
+		Exchange:       exchange,
+		Type:           market,
+		Symbol:         s.Symbol,
+		InstrumentInfo: s.instrumentInfo(),
+		ListedAt:       parseMillis(s.LaunchTime),
+		CreatedAt:      time.Now(),
+	}
+	// perpetuals report deliveryTime "0"
+	if market.IsDelivery() || market == models.MarketOption {
+		symbol.DeliveryAt = parseMillis(s.DeliveryTime)
+	}
+	return symbol
+}
+
+// marketType maps the v5 contractType (LinearPerpetual, LinearFutures,
+// InversePerpetual, InverseFutures) to a market type.
+func (s BybitFuturesSymbol) marketType() models.MarketType {
+	switch s.ContractType {
+	case "LinearFutures":
+		return models.MarketLinearDelivery
+	case "InversePerpetual":
+		return models.MarketInversePerpetual
+	case "InverseFutures":
+		return models.MarketInverseDelivery
+	default:
+		return models.MarketLinearPerpetual
+	}
+}
+
+func (s BybitFuturesSymbol) instrumentInfo() models.InstrumentInfo {
+	return models.InstrumentInfo{
+		BaseAsset:         s.BaseCoin,
+		QuoteAsset:        s.QuoteCoin,
+		SettleAsset:       s.SettleCoin,
+		ExchangeStatus:    s.Status,
+		TickSize:          s.PriceFilter.TickSize,
+		LotSize:           s.LotSizeFilter.QtyStep,
+		MinQty:            s.LotSizeFilter.MinOrderQty,
+		MinNotional:       s.LotSizeFilter.MinNotionalValue,
+		PricePrecision:    atoiOrZero(s.PriceScale),
+		QuantityPrecision: decimalPlaces(s.LotSizeFilter.QtyStep),
+		ContractSize:      "1",
+		ContractType:      firstNonEmpty(s.ContractType, s.OptionsType),
+	}
+}
+
+// checkBybitCode turns a non-zero Bybit retCode into an *ExchangeError.
+func checkBybitCode(retCode int, retMsg, market string) error {
+	code := strconv.Itoa(retCode)
+	switch retCode {
+	case 0:
+		return nil
+	case 10006, 10018:
+		return newExchangeError(ErrRateLimited, "bybit", market, 0, code, retMsg)
+	case 10016:
+		return newExchangeError(ErrExchangeMaintenance, "bybit", market, 0, code, retMsg)
+	default:
+		return newExchangeError(ErrBadPayload, "bybit", market, 0, code, retMsg)
+	}
 }
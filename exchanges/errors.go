@@ -0,0 +1,73 @@
+package exchanges
+
+import (
+	"all_exchange_symbol/models"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// Sentinel errors for classifying failed exchange calls. Use errors.Is to
+// test the category and errors.As with *ExchangeError for the details.
+var (
+	ErrRateLimited         = errors.New("rate limited")
+	ErrExchangeMaintenance = errors.New("exchange under maintenance")
+	ErrBadPayload          = errors.New("bad payload")
+	ErrUnexpectedStatus    = errors.New("unexpected HTTP status")
+	ErrUnsupportedMarket   = errors.New("unsupported market type")
+)
+
+type ExchangeError struct {
+	Kind       error
+	Exchange   string
+	Market     string
+	StatusCode int
+	Code       string
+	Message    string
+}
+
+func (e *ExchangeError) Error() string {
+	msg := fmt.Sprintf("%s %s: %v", e.Exchange, e.Market, e.Kind)
+	if e.StatusCode != 0 {
+		msg += fmt.Sprintf(" (http %d)", e.StatusCode)
+	}
+	if e.Code != "" {
+		msg += fmt.Sprintf(" code=%s", e.Code)
+	}
+	if e.Message != "" {
+		msg += fmt.Sprintf(" msg=%s", e.Message)
+	}
+	return msg
+}
+
+func (e *ExchangeError) Unwrap() error {
+	return e.Kind
+}
+
+func newExchangeError(kind error, exchange, market string, statusCode int, code, message string) *ExchangeError {
+	return &ExchangeError{
+		Kind:       kind,
+		Exchange:   exchange,
+		Market:     market,
+		StatusCode: statusCode,
+		Code:       code,
+		Message:    message,
+	}
+}
+
+// classifyStatus maps a non-200 HTTP status to an error category.
+func classifyStatus(statusCode int) error {
+	switch {
+	case statusCode == http.StatusTooManyRequests || statusCode == http.StatusTeapot:
+		// Binance answers 418 once an IP is banned for ignoring 429s
+		return ErrRateLimited
+	case statusCode == http.StatusServiceUnavailable:
+		return ErrExchangeMaintenance
+	default:
+		return ErrUnexpectedStatus
+	}
+}
+
+func unsupportedMarket(exchange string, market models.MarketType) error {
+	return fmt.Errorf("%s: %w %s", exchange, ErrUnsupportedMarket, market)
+}
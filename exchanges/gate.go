@@ -2,14 +2,16 @@ package exchanges
 
 import (
 	"all_exchange_symbol/models"
-	"encoding/json"
-	"io"
-	"net/http"
+	"context"
+	"net/url"
+	"strconv"
+	"strings"
 	"time"
 )
 
 type Gate struct {
-	Name string
+	Name   string
+	client *HTTPClient
 }
 
 type GateSymbol struct {
@@ -24,49 +26,138 @@ type GateSymbol struct {
 	TradeStatus     string `json:"trade_status"`
 	SellStart       int64  `json:"sell_start"`
 	BuyStart        int64  `json:"buy_start"`
+	DelistingTime   int64  `json:"delisting_time"`
 }
 
 type GateFuturesContract struct {
-	Name        string `json:"name"`
-	Type        string `json:"type"`
-	Quanto      bool   `json:"quanto"`
-	Leverage    string `json:"leverage"`
-	InDelisting bool   `json:"in_delisting"`
-	TradeStatus string `json:"trade_status"`
+	Name             string  `json:"name"`
+	Type             string  `json:"type"`
+	Quanto           bool    `json:"quanto"`
+	Leverage         string  `json:"leverage"`
+	InDelisting      bool    `json:"in_delisting"`
+	TradeStatus      string  `json:"trade_status"`
+	QuantoMultiplier string  `json:"quanto_multiplier"`
+	OrderPriceRound  string  `json:"order_price_round"`
+	OrderSizeMin     int64   `json:"order_size_min"`
+	CreateTime       float64 `json:"create_time"`
+}
+
+type GateDeliveryContract struct {
+	Name             string  `json:"name"`
+	Underlying       string  `json:"underlying"`
+	Cycle            string  `json:"cycle"`
+	Type             string  `json:"type"`
+	InDelisting      bool    `json:"in_delisting"`
+	TradeStatus      string  `json:"trade_status"`
+	QuantoMultiplier string  `json:"quanto_multiplier"`
+	OrderPriceRound  string  `json:"order_price_round"`
+	OrderSizeMin     int64   `json:"order_size_min"`
+	ExpireTime       int64   `json:"expire_time"`
+	CreateTime       float64 `json:"create_time"`
+}
+
+type GateOptionContract struct {
+	Name            string  `json:"name"`
+	Underlying      string  `json:"underlying"`
+	IsCall          bool    `json:"is_call"`
+	Multiplier      string  `json:"multiplier"`
+	OrderPriceRound string  `json:"order_price_round"`
+	OrderSizeMin    int64   `json:"order_size_min"`
+	CreateTime      float64 `json:"create_time"`
+	ExpirationTime  int64   `json:"expiration_time"`
+}
+
+var gateMarkets = []models.MarketType{
+	models.MarketSpot,
+	models.MarketLinearPerpetual,
+	models.MarketInversePerpetual,
+	models.MarketLinearDelivery,
+	models.MarketOption,
+}
+
+func init() {
+	Register(Registration{
+		Name:       "gate",
+		Markets:    gateMarkets,
+		HTTPConfig: gateHTTPConfig(),
+		New: func(config HTTPConfig) ExchangeInterface {
+			return newGateWithConfig(config)
+		},
+	})
+}
+
+func gateHTTPConfig() HTTPConfig {
+	config := DefaultHTTPConfig()
+	// public endpoints allow 200 requests per 10 seconds
+	config.RequestsPerSecond = 10
+	config.Burst = 10
+
+	return config
 }
 
 func NewGate() *Gate {
-	return &Gate{Name: "gate"}
+	return newGateWithConfig(gateHTTPConfig())
+}
+
+func newGateWithConfig(config HTTPConfig) *Gate {
+	return &Gate{
+		Name:   "gate",
+		client: NewHTTPClient("gate", config),
+	}
 }
 
 func (g *Gate) GetName() string {
 	return g.Name
 }
 
-func (g *Gate) FetchSpotSymbols() ([]models.Symbol, error) {
-	resp, err := http.Get("https://api.gateio.ws/api/v4/spot/currency_pairs")
-	if err != nil {
-		return nil, err
-	}
-	defer resp.Body.Close()
+func (g *Gate) SupportedMarkets() []models.MarketType {
+	return gateMarkets
+}
 
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, err
+func (g *Gate) FetchSymbols(ctx context.Context, market models.MarketType) ([]models.Symbol, error) {
+	switch market {
+	case models.MarketSpot:
+		return g.fetchSpotSymbols(ctx)
+	case models.MarketLinearPerpetual:
+		return g.fetchFuturesSymbols(ctx, market, "usdt")
+	case models.MarketInversePerpetual:
+		// BTC-settled perpetuals such as BTC_USD are coin-margined
+		return g.fetchFuturesSymbols(ctx, market, "btc")
+	case models.MarketLinearDelivery:
+		return g.fetchDeliverySymbols(ctx, market, "usdt")
+	case models.MarketOption:
+		return g.fetchOptionSymbols(ctx)
+	default:
+		return nil, unsupportedMarket(g.Name, market)
 	}
+}
 
+func (g *Gate) fetchSpotSymbols(ctx context.Context) ([]models.Symbol, error) {
 	var result []GateSymbol
 
-	if err := json.Unmarshal(body, &result); err != nil {
+	if err := g.client.GetJSON(ctx, "spot", "https://api.gateio.ws/api/v4/spot/currency_pairs", &result); err != nil {
 		return nil, err
 	}
 
 	var symbols []models.Symbol
 	for _, s := range result {
 		symbols = append(symbols, models.Symbol{
-			Exchange:  g.Name,
-			Type:      "spot",
-			Symbol:    s.Id,
+			Exchange: g.Name,
+			Type:     models.MarketSpot,
+			Symbol:   s.Id,
+			InstrumentInfo: models.InstrumentInfo{
+				BaseAsset:          s.Base,
+				QuoteAsset:         s.Quote,
+				ExchangeStatus:     s.TradeStatus,
+				TickSize:           precisionToStep(s.Precision),
+				LotSize:            precisionToStep(s.AmountPrecision),
+				MinQty:             s.MinBaseAmount,
+				MinNotional:        s.MinQuoteAmount,
+				PricePrecision:     s.Precision,
+				QuantityPrecision:  s.AmountPrecision,
+				DelistingScheduled: s.DelistingTime > 0,
+			},
+			ListedAt:  parseSeconds(s.BuyStart),
 			CreatedAt: time.Now(),
 		})
 	}
@@ -74,33 +165,141 @@ func (g *Gate) FetchSpotSymbols() ([]models.Symbol, error) {
 	return symbols, nil
 }
 
-func (g *Gate) FetchFuturesSymbols() ([]models.Symbol, error) {
-	resp, err := http.Get("https://api.gateio.ws/api/v4/futures/usdt/contracts")
-	if err != nil {
+func (g *Gate) fetchFuturesSymbols(ctx context.Context, market models.MarketType, settle string) ([]models.Symbol, error) {
+	var result []GateFuturesContract
+
+	if err := g.client.GetJSON(ctx, string(market), "https://api.gateio.ws/api/v4/futures/"+settle+"/contracts", &result); err != nil {
 		return nil, err
 	}
-	defer resp.Body.Close()
 
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, err
+	var symbols []models.Symbol
+	for _, s := range result {
+		base, quote, _ := strings.Cut(s.Name, "_")
+		symbols = append(symbols, models.Symbol{
+			Exchange: g.Name,
+			Type:     market,
+			Symbol:   s.Name,
+			InstrumentInfo: models.InstrumentInfo{
+				BaseAsset:          base,
+				QuoteAsset:         quote,
+				SettleAsset:        strings.ToUpper(settle),
+				ExchangeStatus:     s.TradeStatus,
+				TickSize:           s.OrderPriceRound,
+				LotSize:            "1", // contracts are traded in whole units
+				MinQty:             strconv.FormatInt(s.OrderSizeMin, 10),
+				PricePrecision:     decimalPlaces(s.OrderPriceRound),
+				QuantityPrecision:  0,
+				ContractSize:       s.QuantoMultiplier,
+				ContractType:       s.Type,
+				DelistingScheduled: gateDelisting(s.InDelisting, s.TradeStatus),
+			},
+			ListedAt:  parseFloatSeconds(s.CreateTime),
+			CreatedAt: time.Now(),
+		})
 	}
 
-	var result []GateFuturesContract
+	return symbols, nil
+}
+
+func (g *Gate) fetchDeliverySymbols(ctx context.Context, market models.MarketType, settle string) ([]models.Symbol, error) {
+	var result []GateDeliveryContract
 
-	if err := json.Unmarshal(body, &result); err != nil {
+	if err := g.client.GetJSON(ctx, string(market), "https://api.gateio.ws/api/v4/delivery/"+settle+"/contracts", &result); err != nil {
 		return nil, err
 	}
 
 	var symbols []models.Symbol
 	for _, s := range result {
-		symbols = append(symbols, models.Symbol{
-			Exchange:  g.Name,
-			Type:      "futures",
-			Symbol:    s.Name,
+		base, quote, _ := strings.Cut(s.Underlying, "_")
+		symbol := models.Symbol{
+			Exchange: g.Name,
+			Type:     market,
+			Symbol:   s.Name,
+			InstrumentInfo: models.InstrumentInfo{
+				BaseAsset:          base,
+				QuoteAsset:         quote,
+				SettleAsset:        strings.ToUpper(settle),
+				ExchangeStatus:     s.TradeStatus,
+				TickSize:           s.OrderPriceRound,
+				LotSize:            "1",
+				MinQty:             strconv.FormatInt(s.OrderSizeMin, 10),
+				PricePrecision:     decimalPlaces(s.OrderPriceRound),
+				ContractSize:       s.QuantoMultiplier,
+				ContractType:       s.Cycle,
+				DelistingScheduled: gateDelisting(s.InDelisting, s.TradeStatus),
+			},
+			ListedAt:  parseFloatSeconds(s.CreateTime),
 			CreatedAt: time.Now(),
-		})
+		}
+		if s.ExpireTime > 0 {
+			deliveryAt := time.Unix(s.ExpireTime, 0)
+			symbol.DeliveryAt = &deliveryAt
+		}
+		symbols = append(symbols, symbol)
+	}
+
+	return symbols, nil
+}
+
+// fetchOptionSymbols lists option series per underlying, since
+// /options/contracts requires the underlying parameter.
+func (g *Gate) fetchOptionSymbols(ctx context.Context) ([]models.Symbol, error) {
+	var underlyings []struct {
+		Name string `json:"name"`
+	}
+
+	market := string(models.MarketOption)
+	if err := g.client.GetJSON(ctx, market, "https://api.gateio.ws/api/v4/options/underlyings", &underlyings); err != nil {
+		return nil, err
+	}
+
+	var symbols []models.Symbol
+	for _, underlying := range underlyings {
+		var result []GateOptionContract
+
+		endpoint := "https://api.gateio.ws/api/v4/options/contracts?underlying=" + url.QueryEscape(underlying.Name)
+		if err := g.client.GetJSON(ctx, market, endpoint, &result); err != nil {
+			return nil, err
+		}
+
+		for _, s := range result {
+			base, quote, _ := strings.Cut(s.Underlying, "_")
+			optionType := "put"
+			if s.IsCall {
+				optionType = "call"
+			}
+
+			symbol := models.Symbol{
+				Exchange: g.Name,
+				Type:     models.MarketOption,
+				Symbol:   s.Name,
+				InstrumentInfo: models.InstrumentInfo{
+					BaseAsset:      base,
+					QuoteAsset:     quote,
+					SettleAsset:    quote,
+					TickSize:       s.OrderPriceRound,
+					LotSize:        "1",
+					MinQty:         strconv.FormatInt(s.OrderSizeMin, 10),
+					PricePrecision: decimalPlaces(s.OrderPriceRound),
+					ContractSize:   s.Multiplier,
+					ContractType:   optionType,
+				},
+				ListedAt:  parseFloatSeconds(s.CreateTime),
+				CreatedAt: time.Now(),
+			}
+			if s.ExpirationTime > 0 {
+				deliveryAt := time.Unix(s.ExpirationTime, 0)
+				symbol.DeliveryAt = &deliveryAt
+			}
+			symbols = append(symbols, symbol)
+		}
 	}
 
 	return symbols, nil
 }
+
+// gateDelisting reports whether Gate has announced that a contract will be
+// delisted; it keeps trading until it disappears from the list.
+func gateDelisting(inDelisting bool, tradeStatus string) bool {
+	return inDelisting || tradeStatus == "delisting"
+}
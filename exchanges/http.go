@@ -0,0 +1,303 @@
+package exchanges
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const defaultUserAgent = "all_exchange_symbol/1.0 (+symbol-tracker)"
+
+// HTTPConfig controls timeouts, retries, rate limiting and size limits of
+// the HTTP client shared by an exchange adapter.
+type HTTPConfig struct {
+	Timeout           time.Duration
+	MaxRetries        int
+	BaseBackoff       time.Duration
+	MaxBackoff        time.Duration
+	RequestsPerSecond float64
+	Burst             int
+	MaxRequestBytes   int64
+	MaxResponseBytes  int64
+	UserAgent         string
+}
+
+func DefaultHTTPConfig() HTTPConfig {
+	return HTTPConfig{
+		Timeout:           15 * time.Second,
+		MaxRetries:        3,
+		BaseBackoff:       500 * time.Millisecond,
+		MaxBackoff:        10 * time.Second,
+		RequestsPerSecond: 5,
+		Burst:             5,
+		MaxRequestBytes:   64 << 10,
+		MaxResponseBytes:  32 << 20,
+		UserAgent:         defaultUserAgent,
+	}
+}
+
+// HTTPClient wraps http.Client with per-exchange rate limiting and retries
+// with exponential backoff and jitter on 429/5xx responses, or after the
+// server's Retry-After when it sends one.
+type HTTPClient struct {
+	exchange string
+	config   HTTPConfig
+	client   *http.Client
+	limiter  *tokenBucket
+}
+
+func NewHTTPClient(exchange string, config HTTPConfig) *HTTPClient {
+	return &HTTPClient{
+		exchange: exchange,
+		config:   config,
+		client:   &http.Client{Timeout: config.Timeout},
+		limiter:  newTokenBucket(config.RequestsPerSecond, config.Burst),
+	}
+}
+
+// apiErrorBody covers the error payload shapes of the supported exchanges:
+// Binance {"code":-1003,"msg":""}, OKX/Bitget {"code":"","msg":""},
+// Bybit {"retCode":10006,"retMsg":""} and Gate {"label":"","message":""}.
+type apiErrorBody struct {
+	Code    json.RawMessage `json:"code"`
+	Msg     string          `json:"msg"`
+	RetCode json.RawMessage `json:"retCode"`
+	RetMsg  string          `json:"retMsg"`
+	Label   string          `json:"label"`
+	Message string          `json:"message"`
+}
+
+func (b apiErrorBody) code() string {
+	for _, raw := range []json.RawMessage{b.Code, b.RetCode} {
+		if len(raw) > 0 {
+			return strings.Trim(string(raw), `"`)
+		}
+	}
+	return b.Label
+}
+
+func (b apiErrorBody) message() string {
+	for _, msg := range []string{b.Msg, b.RetMsg, b.Message} {
+		if msg != "" {
+			return msg
+		}
+	}
+	return ""
+}
+
+// GetJSON fetches url and decodes the body into v. Non-200 responses,
+// oversized and undecodable bodies are returned as *ExchangeError.
+func (c *HTTPClient) GetJSON(ctx context.Context, market, url string, v interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+
+	body, statusCode, err := c.do(req, market)
+	if err != nil {
+		return err
+	}
+
+	if statusCode != http.StatusOK {
+		return c.statusError(market, statusCode, body)
+	}
+
+	if err := json.Unmarshal(body, v); err != nil {
+		return newExchangeError(ErrBadPayload, c.exchange, market, statusCode, "", err.Error())
+	}
+
+	return nil
+}
+
+// do sends req, retrying network errors, 429 and 5xx responses. It returns
+// the body and status of the last attempt.
+func (c *HTTPClient) do(req *http.Request, market string) ([]byte, int, error) {
+	if req.ContentLength > c.config.MaxRequestBytes && c.config.MaxRequestBytes > 0 {
+		return nil, 0, fmt.Errorf("%s request body too large: %d bytes", c.exchange, req.ContentLength)
+	}
+	req.Header.Set("User-Agent", c.config.UserAgent)
+	req.Header.Set("Accept", "application/json")
+
+	var lastErr error
+	for attempt := 0; ; attempt++ {
+		if err := c.limiter.Wait(req.Context()); err != nil {
+			return nil, 0, err
+		}
+
+		body, statusCode, retryAfter, err := c.attempt(req, market)
+		if err == nil && !retryableStatus(statusCode) {
+			return body, statusCode, nil
+		}
+		if errors.Is(err, ErrBadPayload) || req.Context().Err() != nil {
+			return nil, 0, err
+		}
+		if attempt >= c.config.MaxRetries {
+			if err != nil {
+				return nil, 0, err
+			}
+			return body, statusCode, nil
+		}
+
+		lastErr = err
+		if lastErr == nil {
+			lastErr = fmt.Errorf("http %d", statusCode)
+		}
+
+		// Retry-After is honored as given: retrying earlier is what gets an IP
+		// banned (Binance answers 418). If the caller cannot wait that long the
+		// market is reported as rate limited instead.
+		delay := c.backoff(attempt)
+		if retryAfter > 0 {
+			delay = retryAfter
+			if deadline, ok := req.Context().Deadline(); ok && time.Until(deadline) < delay {
+				return nil, 0, newExchangeError(ErrRateLimited, c.exchange, market, statusCode, "",
+					fmt.Sprintf("Retry-After %v exceeds the remaining deadline", retryAfter))
+			}
+		}
+
+		log.Printf("%s %s 请求失败 (%v)，%v 后进行第 %d/%d 次重试",
+			c.exchange, req.URL.Path, lastErr, delay, attempt+1, c.config.MaxRetries)
+		if err := sleepContext(req.Context(), delay); err != nil {
+			return nil, 0, err
+		}
+	}
+}
+
+func (c *HTTPClient) attempt(req *http.Request, market string) ([]byte, int, time.Duration, error) {
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, 0, 0, err
+	}
+	defer resp.Body.Close()
+
+	reader := io.Reader(resp.Body)
+	if c.config.MaxResponseBytes > 0 {
+		reader = io.LimitReader(resp.Body, c.config.MaxResponseBytes+1)
+	}
+
+	body, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, 0, 0, err
+	}
+	if c.config.MaxResponseBytes > 0 && int64(len(body)) > c.config.MaxResponseBytes {
+		return nil, 0, 0, newExchangeError(ErrBadPayload, c.exchange, market, resp.StatusCode, "",
+			fmt.Sprintf("response exceeds %d bytes", c.config.MaxResponseBytes))
+	}
+
+	return body, resp.StatusCode, parseRetryAfter(resp.Header.Get("Retry-After")), nil
+}
+
+func (c *HTTPClient) statusError(market string, statusCode int, body []byte) error {
+	var apiErr apiErrorBody
+	message := strings.TrimSpace(string(body))
+	code := ""
+	if json.Unmarshal(body, &apiErr) == nil {
+		code = apiErr.code()
+		if m := apiErr.message(); m != "" {
+			message = m
+		}
+	}
+	if len(message) > 200 {
+		message = message[:200]
+	}
+	return newExchangeError(classifyStatus(statusCode), c.exchange, market, statusCode, code, message)
+}
+
+// backoff returns base*2^attempt with full jitter, capped at MaxBackoff.
+func (c *HTTPClient) backoff(attempt int) time.Duration {
+	delay := c.config.BaseBackoff << uint(attempt)
+	if delay <= 0 || delay > c.config.MaxBackoff {
+		delay = c.config.MaxBackoff
+	}
+	return delay/2 + time.Duration(rand.Int63n(int64(delay/2)+1))
+}
+
+func retryableStatus(statusCode int) bool {
+	return statusCode == http.StatusTooManyRequests || statusCode >= http.StatusInternalServerError
+}
+
+// parseRetryAfter accepts both delay-seconds and HTTP-date forms.
+func parseRetryAfter(value string) time.Duration {
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(strings.TrimSpace(value)); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if at, err := http.ParseTime(value); err == nil {
+		if delay := time.Until(at); delay > 0 {
+			return delay
+		}
+	}
+	return 0
+}
+
+// tokenBucket is a minimal blocking token-bucket rate limiter.
+type tokenBucket struct {
+	mu       sync.Mutex
+	rate     float64
+	capacity float64
+	tokens   float64
+	last     time.Time
+}
+
+func newTokenBucket(ratePerSecond float64, burst int) *tokenBucket {
+	if burst < 1 {
+		burst = 1
+	}
+	return &tokenBucket{
+		rate:     ratePerSecond,
+		capacity: float64(burst),
+		tokens:   float64(burst),
+		last:     time.Now(),
+	}
+}
+
+func (b *tokenBucket) Wait(ctx context.Context) error {
+	if b.rate <= 0 {
+		return nil
+	}
+	for {
+		b.mu.Lock()
+		now := time.Now()
+		b.tokens += now.Sub(b.last).Seconds() * b.rate
+		if b.tokens > b.capacity {
+			b.tokens = b.capacity
+		}
+		b.last = now
+
+		if b.tokens >= 1 {
+			b.tokens--
+			b.mu.Unlock()
+			return nil
+		}
+
+		wait := time.Duration((1 - b.tokens) / b.rate * float64(time.Second))
+		b.mu.Unlock()
+		if err := sleepContext(ctx, wait); err != nil {
+			return err
+		}
+	}
+}
+
+// sleepContext waits for d or until ctx is done, whichever comes first.
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
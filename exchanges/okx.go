@@ -2,94 +2,247 @@ package exchanges
 
 import (
 	"all_exchange_symbol/models"
-	"encoding/json"
-	"io"
-	"net/http"
+	"context"
+	"net/url"
+	"strings"
 	"time"
 )
 
 type OKX struct {
-	Name string
+	Name   string
+	client *HTTPClient
 }
 
 type OKXInstrument struct {
-	InstType string `json:"instType"`
-	InstId   string `json:"instId"`
-	State    string `json:"state"`
+	InstType  string `json:"instType"`
+	InstId    string `json:"instId"`
+	State     string `json:"state"`
+	BaseCcy   string `json:"baseCcy"`
+	QuoteCcy  string `json:"quoteCcy"`
+	SettleCcy string `json:"settleCcy"`
+	CtValCcy  string `json:"ctValCcy"`
+	CtVal     string `json:"ctVal"`
+	CtType    string `json:"ctType"`
+	Alias     string `json:"alias"`
+	OptType   string `json:"optType"`
+	ListTime  string `json:"listTime"`
+	ExpTime   string `json:"expTime"`
+	TickSz    string `json:"tickSz"`
+	LotSz     string `json:"lotSz"`
+	MinSz     string `json:"minSz"`
+	Uly       string `json:"uly"`
+}
+
+var okxMarkets = []models.MarketType{
+	models.MarketSpot,
+	models.MarketMargin,
+	models.MarketLinearPerpetual,
+	models.MarketInversePerpetual,
+	models.MarketLinearDelivery,
+	models.MarketInverseDelivery,
+	models.MarketOption,
+}
+
+func init() {
+	Register(Registration{
+		Name:       "okx",
+		Markets:    okxMarkets,
+		HTTPConfig: okxHTTPConfig(),
+		New: func(config HTTPConfig) ExchangeInterface {
+			return newOKXWithConfig(config)
+		},
+	})
+}
+
+func okxHTTPConfig() HTTPConfig {
+	config := DefaultHTTPConfig()
+	// public instruments endpoint allows 20 requests per 2 seconds
+	config.RequestsPerSecond = 10
+	config.Burst = 10
+
+	return config
 }
 
 func NewOKX() *OKX {
-	return &OKX{Name: "okx"}
+	return newOKXWithConfig(okxHTTPConfig())
+}
+
+func newOKXWithConfig(config HTTPConfig) *OKX {
+	return &OKX{
+		Name:   "okx",
+		client: NewHTTPClient("okx", config),
+	}
 }
 
 func (o *OKX) GetName() string {
 	return o.Name
 }
 
-func (o *OKX) FetchSpotSymbols() ([]models.Symbol, error) {
-	resp, err := http.Get("https://www.okx.com/api/v5/public/instruments?instType=SPOT")
-	if err != nil {
-		return nil, err
+func (o *OKX) SupportedMarkets() []models.MarketType {
+	return okxMarkets
+}
+
+func (o *OKX) FetchSymbols(ctx context.Context, market models.MarketType) ([]models.Symbol, error) {
+	switch market {
+	case models.MarketSpot:
+		return o.fetchMarket(ctx, market, "SPOT")
+	case models.MarketMargin:
+		return o.fetchMarket(ctx, market, "MARGIN")
+	case models.MarketLinearPerpetual, models.MarketInversePerpetual:
+		return o.fetchMarket(ctx, market, "SWAP")
+	case models.MarketLinearDelivery, models.MarketInverseDelivery:
+		return o.fetchMarket(ctx, market, "FUTURES")
+	case models.MarketOption:
+		return o.fetchOptionSymbols(ctx)
+	default:
+		return nil, unsupportedMarket(o.Name, market)
 	}
-	defer resp.Body.Close()
+}
 
-	body, err := io.ReadAll(resp.Body)
+// fetchMarket lists one instType and keeps the instruments of market; SWAP
+// and FUTURES mix linear and inverse contracts in one response.
+func (o *OKX) fetchMarket(ctx context.Context, market models.MarketType, instType string) ([]models.Symbol, error) {
+	instruments, err := o.fetchInstruments(ctx, market, "instType="+instType)
 	if err != nil {
 		return nil, err
 	}
 
+	var symbols []models.Symbol
+	for _, s := range instruments {
+		if s.marketType() != market {
+			continue
+		}
+		symbols = append(symbols, s.symbol(o.Name))
+	}
+
+	return symbols, nil
+}
+
+// fetchOptionSymbols lists option series per underlying, since the
+// instruments endpoint requires uly (or instFamily) for OPTION.
+func (o *OKX) fetchOptionSymbols(ctx context.Context) ([]models.Symbol, error) {
 	var result struct {
-		Code string          `json:"code"`
-		Data []OKXInstrument `json:"data"`
+		Code string     `json:"code"`
+		Msg  string     `json:"msg"`
+		Data [][]string `json:"data"`
+	}
+
+	market := string(models.MarketOption)
+	if err := o.client.GetJSON(ctx, market, "https://www.okx.com/api/v5/public/underlying?instType=OPTION", &result); err != nil {
+		return nil, err
 	}
 
-	if err := json.Unmarshal(body, &result); err != nil {
+	if err := checkOKXCode(result.Code, result.Msg, market); err != nil {
 		return nil, err
 	}
 
 	var symbols []models.Symbol
-	for _, s := range result.Data {
-		symbols = append(symbols, models.Symbol{
-			Exchange:  o.Name,
-			Type:      "spot",
-			Symbol:    s.InstId,
-			CreatedAt: time.Now(),
-		})
+	for _, underlyings := range result.Data {
+		for _, uly := range underlyings {
+			instruments, err := o.fetchInstruments(ctx, models.MarketOption, "instType=OPTION&uly="+url.QueryEscape(uly))
+			if err != nil {
+				return nil, err
+			}
+			for _, s := range instruments {
+				symbols = append(symbols, s.symbol(o.Name))
+			}
+		}
 	}
 
 	return symbols, nil
 }
 
-func (o *OKX) FetchFuturesSymbols() ([]models.Symbol, error) {
-	resp, err := http.Get("https://www.okx.com/api/v5/public/instruments?instType=SWAP")
-	if err != nil {
+func (o *OKX) fetchInstruments(ctx context.Context, market models.MarketType, query string) ([]OKXInstrument, error) {
+	var result struct {
+		Code string          `json:"code"`
+		Msg  string          `json:"msg"`
+		Data []OKXInstrument `json:"data"`
+	}
+
+	if err := o.client.GetJSON(ctx, string(market), "https://www.okx.com/api/v5/public/instruments?"+query, &result); err != nil {
 		return nil, err
 	}
-	defer resp.Body.Close()
 
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
+	if err := checkOKXCode(result.Code, result.Msg, string(market)); err != nil {
 		return nil, err
 	}
 
-	var result struct {
-		Code string          `json:"code"`
-		Data []OKXInstrument `json:"data"`
+	return result.Data, nil
+}
+
+// marketType classifies an instrument by instType and ctType: linear
+// contracts are margined in the quote currency, inverse ones in the base coin.
+func (s OKXInstrument) marketType() models.MarketType {
+	switch s.InstType {
+	case "SPOT":
+		return models.MarketSpot
+	case "MARGIN":
+		return models.MarketMargin
+	case "OPTION":
+		return models.MarketOption
+	case "FUTURES":
+		if s.CtType == "inverse" {
+			return models.MarketInverseDelivery
+		}
+		return models.MarketLinearDelivery
+	default:
+		if s.CtType == "inverse" {
+			return models.MarketInversePerpetual
+		}
+		return models.MarketLinearPerpetual
 	}
+}
 
-	if err := json.Unmarshal(body, &result); err != nil {
-		return nil, err
+func (s OKXInstrument) symbol(exchange string) models.Symbol {
+	symbol := models.Symbol{
+		Exchange:       exchange,
+		Type:           s.marketType(),
+		Symbol:         s.InstId,
+		InstrumentInfo: s.instrumentInfo(),
+		ListedAt:       parseMillis(s.ListTime),
+		CreatedAt:      time.Now(),
+	}
+	if s.InstType == "FUTURES" || s.InstType == "OPTION" {
+		symbol.DeliveryAt = parseMillis(s.ExpTime)
+	}
+	return symbol
+}
+
+// instrumentInfo maps OKX fields; derivatives leave baseCcy/quoteCcy empty,
+// so they are taken from the underlying (e.g. BTC-USDT).
+func (s OKXInstrument) instrumentInfo() models.InstrumentInfo {
+	base, quote := s.BaseCcy, s.QuoteCcy
+	if base == "" && s.Uly != "" {
+		if parts := strings.SplitN(s.Uly, "-", 2); len(parts) == 2 {
+			base, quote = parts[0], parts[1]
+		}
 	}
 
-	var symbols []models.Symbol
-	for _, s := range result.Data {
-		symbols = append(symbols, models.Symbol{
-			Exchange:  o.Name,
-			Type:      "futures",
-			Symbol:    s.InstId,
-			CreatedAt: time.Now(),
-		})
+	return models.InstrumentInfo{
+		BaseAsset:         base,
+		QuoteAsset:        quote,
+		SettleAsset:       s.SettleCcy,
+		ExchangeStatus:    s.State,
+		TickSize:          s.TickSz,
+		LotSize:           s.LotSz,
+		MinQty:            s.MinSz,
+		PricePrecision:    decimalPlaces(s.TickSz),
+		QuantityPrecision: decimalPlaces(s.LotSz),
+		ContractSize:      s.CtVal,
+		ContractType:      firstNonEmpty(s.Alias, s.OptType),
 	}
+}
 
-	return symbols, nil
+// checkOKXCode turns a non-zero OKX business code into an *ExchangeError.
+func checkOKXCode(code, msg, market string) error {
+	switch code {
+	case "0":
+		return nil
+	case "50011", "50061":
+		return newExchangeError(ErrRateLimited, "okx", market, 0, code, msg)
+	case "50001", "50013":
+		return newExchangeError(ErrExchangeMaintenance, "okx", market, 0, code, msg)
+	default:
+		return newExchangeError(ErrBadPayload, "okx", market, 0, code, msg)
+	}
 }
@@ -0,0 +1,72 @@
+package exchanges
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// decimalPlaces returns the number of significant decimals of a step such
+// as "0.00100000" (3) or "1" (0).
+func decimalPlaces(step string) int {
+	dot := strings.IndexByte(step, '.')
+	if dot < 0 {
+		return 0
+	}
+	return len(strings.TrimRight(step[dot+1:], "0"))
+}
+
+// precisionToStep converts a decimal count into a step: 2 -> "0.01", 0 -> "1".
+func precisionToStep(precision int) string {
+	if precision <= 0 {
+		return "1"
+	}
+	return "0." + strings.Repeat("0", precision-1) + "1"
+}
+
+// atoiOrZero parses exchanges that send integers as strings.
+func atoiOrZero(value string) int {
+	n, err := strconv.Atoi(strings.TrimSpace(value))
+	if err != nil {
+		return 0
+	}
+	return n
+}
+
+// parseMillis converts a millisecond epoch sent as a string; empty or zero
+// values mean the exchange did not report a time.
+func parseMillis(value string) *time.Time {
+	ms, err := strconv.ParseInt(strings.TrimSpace(value), 10, 64)
+	if err != nil {
+		return nil
+	}
+	return unixMillis(ms)
+}
+
+// unixMillis converts a millisecond epoch; zero means not reported.
+func unixMillis(ms int64) *time.Time {
+	if ms <= 0 {
+		return nil
+	}
+	t := time.UnixMilli(ms)
+	return &t
+}
+
+// parseSeconds converts a second epoch; zero means not reported.
+func parseSeconds(seconds int64) *time.Time {
+	if seconds <= 0 {
+		return nil
+	}
+	t := time.Unix(seconds, 0)
+	return &t
+}
+
+// parseFloatSeconds converts a second epoch with a fractional part, as Gate
+// sends its create_time; zero means not reported.
+func parseFloatSeconds(seconds float64) *time.Time {
+	if seconds <= 0 {
+		return nil
+	}
+	t := time.UnixMilli(int64(seconds * 1000))
+	return &t
+}
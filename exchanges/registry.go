@@ -0,0 +1,111 @@
+package exchanges
+
+import (
+	"all_exchange_symbol/models"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+var ErrUnknownExchange = errors.New("unknown exchange")
+
+// Registration describes an exchange adapter. Adapters register themselves
+// from init() so adding an exchange only touches its own file.
+type Registration struct {
+	Name       string
+	Markets    []models.MarketType
+	HTTPConfig HTTPConfig
+	New        func(config HTTPConfig) ExchangeInterface
+}
+
+type Registry struct {
+	mu      sync.RWMutex
+	entries map[string]Registration
+}
+
+func NewRegistry() *Registry {
+	return &Registry{entries: make(map[string]Registration)}
+}
+
+// DefaultRegistry holds every adapter compiled into the binary.
+var DefaultRegistry = NewRegistry()
+
+func Register(registration Registration) {
+	DefaultRegistry.Register(registration)
+}
+
+func (r *Registry) Register(registration Registration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.entries[registration.Name]; exists {
+		panic(fmt.Sprintf("exchange %s registered twice", registration.Name))
+	}
+	r.entries[registration.Name] = registration
+}
+
+// Names returns all registered exchange names in alphabetical order.
+func (r *Registry) Names() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	names := make([]string, 0, len(r.entries))
+	for name := range r.entries {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (r *Registry) Get(name string) (Registration, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	registration, ok := r.entries[name]
+	if !ok {
+		return Registration{}, r.unknown(name)
+	}
+	return registration, nil
+}
+
+// Build instantiates the enabled adapters. An empty enabled list means all
+// registered exchanges; disabled always wins over enabled.
+func (r *Registry) Build(enabled, disabled []string) ([]ExchangeInterface, error) {
+	names := enabled
+	if len(names) == 0 {
+		names = r.Names()
+	}
+
+	skip := make(map[string]bool, len(disabled))
+	for _, name := range disabled {
+		if _, err := r.Get(name); err != nil {
+			return nil, err
+		}
+		skip[name] = true
+	}
+
+	var result []ExchangeInterface
+	for _, name := range names {
+		registration, err := r.Get(name)
+		if err != nil {
+			return nil, err
+		}
+		if skip[name] {
+			continue
+		}
+		result = append(result, registration.New(registration.HTTPConfig))
+	}
+
+	return result, nil
+}
+
+func (r *Registry) unknown(name string) error {
+	names := make([]string, 0, len(r.entries))
+	for registered := range r.entries {
+		names = append(names, registered)
+	}
+	sort.Strings(names)
+	return fmt.Errorf("%w %q (available: %s)", ErrUnknownExchange, name, strings.Join(names, ", "))
+}
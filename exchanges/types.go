@@ -1,11 +1,25 @@
 package exchanges
 
-import "all_exchange_symbol/models"
+import (
+	"all_exchange_symbol/models"
+	"context"
+)
 
 type ExchangeInterface interface {
 	GetName() string
-	FetchSpotSymbols() ([]models.Symbol, error)
-	FetchFuturesSymbols() ([]models.Symbol, error)
+	// SupportedMarkets lists the market types FetchSymbols accepts.
+	SupportedMarkets() []models.MarketType
+	// FetchSymbols returns every instrument of one market type. Adapters
+	// whose endpoint mixes several types filter the response.
+	FetchSymbols(ctx context.Context, market models.MarketType) ([]models.Symbol, error)
+}
+
+// OptionCoinSeeder is implemented by exchanges that list options per
+// underlying and discover the underlyings at runtime. Seeding them with the
+// underlyings of the stored options keeps those options fetched after a
+// restart instead of reading them as delisted until they are found again.
+type OptionCoinSeeder interface {
+	SeedOptionCoins(coins []string)
 }
 
 type BaseSymbol struct {
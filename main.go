@@ -3,22 +3,34 @@ package main
 import (
 	"all_exchange_symbol/config"
 	"all_exchange_symbol/database"
+	"all_exchange_symbol/exchanges"
 	"all_exchange_symbol/models"
+	"all_exchange_symbol/normalizer"
+	"all_exchange_symbol/notifier"
 	"all_exchange_symbol/processor"
 	"all_exchange_symbol/reader"
+	"all_exchange_symbol/store"
 	"all_exchange_symbol/writer"
+	"context"
 	"flag"
 	"log"
+	"math/rand"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
 	"time"
 )
 
 func main() {
 	var (
-		exchangeFlag = flag.String("exchange", "", "Fetch symbols from specific exchange (binance, okx, gate, bitget, bybit)")
+		exchangeFlag = flag.String("exchange", "", "Fetch symbols from specific exchange ("+strings.Join(exchanges.DefaultRegistry.Names(), ", ")+")")
 		helpFlag     = flag.Bool("help", false, "Show help information")
 		statsFlag    = flag.Bool("stats", false, "Show database statistics")
 		verifyFlag   = flag.Bool("verify", false, "Compare API data with database data for detailed verification")
-		daemonFlag   = flag.Bool("daemon", false, "Run in daemon mode with 5-second periodic checks")
+		lookupFlag   = flag.String("lookup", "", "List every exchange that trades a canonical symbol, e.g. BTC/USDT or BTC/USDT:USDT")
+		daemonFlag   = flag.Bool("daemon", false, "Run in daemon mode with periodic checks (DAEMON_INTERVAL, default 5s)")
+		backfillFlag = flag.Bool("backfill-listed-at", false, "Fill listed_at of stored symbols from exchange listing times")
 	)
 	flag.Parse()
 
@@ -29,40 +41,85 @@ func main() {
 
 	cfg := config.Load()
 
-	database.Initialize()
+	database.Initialize(cfg)
 	defer database.Close()
 
+	symbolStore := store.NewGormStore(database.DB)
+
+	migrated, err := symbolStore.MigrateLegacyFutures(context.Background(), normalizer.ClassifyContract)
+	if err != nil {
+		log.Fatalf("Failed to reclassify legacy futures symbols: %v", err)
+	}
+	if migrated > 0 {
+		log.Printf("Reclassified %d legacy futures symbols by market type", migrated)
+	}
+
+	renamed, err := symbolStore.RenameSymbols(context.Background(), "bitget", exchanges.BitgetV2Symbol)
+	if err != nil {
+		log.Fatalf("Failed to migrate Bitget symbols to v2 names: %v", err)
+	}
+	if renamed > 0 {
+		log.Printf("Migrated %d Bitget symbols to v2 names", renamed)
+	}
+
+	// SIGINT/SIGTERM cancel every in-flight HTTP request and DB query
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go func() {
+		// restore default handling so a second signal terminates immediately
+		<-ctx.Done()
+		stop()
+	}()
+
 	if *statsFlag {
-		showStats()
+		showStats(ctx, symbolStore)
+		return
+	}
+
+	if *lookupFlag != "" {
+		showCanonicalLookup(ctx, symbolStore, *lookupFlag)
 		return
 	}
 
 	if *verifyFlag {
-		showDataVerification(*exchangeFlag)
+		showDataVerification(ctx, symbolStore, *exchangeFlag, cfg)
+		return
+	}
+
+	if *backfillFlag {
+		backfillListedAt(ctx, symbolStore, *exchangeFlag, cfg)
 		return
 	}
 
 	if *daemonFlag {
-		runDaemon(*exchangeFlag, cfg)
+		runDaemon(ctx, symbolStore, *exchangeFlag, cfg)
 		return
 	}
 
 	log.Println("Starting exchange symbol synchronization...")
 	start := time.Now()
 
-	r := reader.NewReader()
-	p := processor.NewProcessor()
-	w := writer.NewWriter(cfg.TelegramBotToken, cfg.TelegramChatID)
+	ctx, cancel := context.WithTimeout(ctx, cfg.SyncTimeout)
+	defer cancel()
+
+	r := newReader(ctx, symbolStore, cfg)
+	p := newProcessor(symbolStore, cfg)
+	w := writer.NewWriter(symbolStore, newNotifiers(cfg))
+	w.SetNotificationRetention(cfg.NotificationRetention)
 
 	var fetchedSymbols []models.Symbol
-	var err error
+	var report *models.FetchReport
 
 	if *exchangeFlag != "" {
 		log.Printf("Fetching symbols from %s only", *exchangeFlag)
-		fetchedSymbols, err = r.FetchSymbolsByExchange(*exchangeFlag)
+		fetchedSymbols, report, err = r.FetchSymbolsByExchange(ctx, *exchangeFlag)
 	} else {
 		log.Println("Fetching symbols from all exchanges")
-		fetchedSymbols, err = r.FetchAllSymbols()
+		fetchedSymbols, report, err = r.FetchAllSymbols(ctx)
+	}
+
+	if writeErr := w.WriteFetchReport(ctx, report); writeErr != nil {
+		log.Printf("Error saving fetch report: %v", writeErr)
 	}
 
 	if err != nil {
@@ -70,9 +127,10 @@ func main() {
 	}
 
 	log.Printf("Fetched %d symbols in %v", len(fetchedSymbols), time.Since(start))
+	logFetchReport(report)
 
 	processStart := time.Now()
-	newSymbols, err := p.ProcessSymbols(fetchedSymbols)
+	changes, err := p.ProcessSymbols(ctx, fetchedSymbols, report)
 	if err != nil {
 		log.Fatalf("Error processing symbols: %v", err)
 	}
@@ -80,54 +138,119 @@ func main() {
 	log.Printf("Processed symbols in %v", time.Since(processStart))
 
 	writeStart := time.Now()
-	if err := w.ProcessAndWrite(newSymbols); err != nil {
+	if err := w.ProcessAndWrite(ctx, changes); err != nil {
 		log.Fatalf("Error writing symbols: %v", err)
 	}
 
 	log.Printf("Wrote symbols in %v", time.Since(writeStart))
 
-	if err := w.SendSummaryToTelegram(len(fetchedSymbols), len(newSymbols)); err != nil {
+	if err := w.SendSummary(ctx, len(fetchedSymbols), changes, report); err != nil {
 		log.Printf("Error sending summary: %v", err)
 	}
 
-	log.Printf("Synchronization completed in %v. Found %d new symbols out of %d total.",
-		time.Since(start), len(newSymbols), len(fetchedSymbols))
+	log.Printf("Synchronization completed in %v. Found %d new, %d delisted and %d relisted symbols out of %d total.",
+		time.Since(start), len(changes.New), len(changes.Delisted), len(changes.Relisted), len(fetchedSymbols))
 }
 
-func runDaemon(exchange string, cfg *config.Config) {
-	log.Println("Starting daemon mode with 5-second intervals...")
+func runDaemon(ctx context.Context, symbolStore store.SymbolStore, exchange string, cfg *config.Config) {
+	log.Printf("Starting daemon mode with %v intervals (jitter up to %v)...", cfg.DaemonInterval, cfg.DaemonJitter)
 	if exchange != "" {
 		log.Printf("Monitoring exchange: %s", exchange)
 	} else {
 		log.Println("Monitoring all exchanges")
 	}
 
-	ticker := time.NewTicker(5 * time.Second)
-	defer ticker.Stop()
+	r := newReader(ctx, symbolStore, cfg)
+	p := newProcessor(symbolStore, cfg)
+	w := writer.NewWriter(symbolStore, newNotifiers(cfg))
+	w.SetNotificationRetention(cfg.NotificationRetention)
 
 	for {
+		cycleStart := time.Now()
+		runCycle(ctx, r, p, w, exchange, cfg)
+
+		if ctx.Err() != nil {
+			break
+		}
+
+		// Cycles run back to back on one goroutine, so they can never overlap;
+		// a slow cycle simply delays the next one.
+		wait := time.Until(cycleStart.Add(cfg.DaemonInterval))
+		if cfg.DaemonJitter > 0 {
+			wait += time.Duration(rand.Int63n(int64(cfg.DaemonJitter)))
+		}
+		if wait < 0 {
+			wait = 0
+		}
+
+		timer := time.NewTimer(wait)
 		select {
-		case <-ticker.C:
-			performSynchronization(exchange, cfg)
+		case <-ctx.Done():
+			timer.Stop()
+		case <-timer.C:
+		}
+
+		if ctx.Err() != nil {
+			break
 		}
 	}
+
+	log.Println("Shutdown signal received, stopping daemon")
+
+	flushCtx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownGracePeriod)
+	defer cancel()
+	if remaining := w.FlushPending(flushCtx); remaining > 0 {
+		log.Printf("%d undelivered notifications stay in the outbox for the next run", remaining)
+	}
 }
 
-func performSynchronization(exchange string, cfg *config.Config) {
+// runCycle runs one synchronization with its own deadline. A shutdown signal
+// gives the cycle ShutdownGracePeriod to finish before it is aborted.
+func runCycle(ctx context.Context, r *reader.Reader, p *processor.Processor, w *writer.Writer, exchange string, cfg *config.Config) {
+	cycleCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), cfg.SyncTimeout)
+	defer cancel()
+
+	done := make(chan struct{})
+	defer close(done)
+
+	go func() {
+		select {
+		case <-done:
+			return
+		case <-ctx.Done():
+		}
+
+		log.Printf("Shutdown requested, waiting up to %v for the current cycle to finish", cfg.ShutdownGracePeriod)
+		timer := time.NewTimer(cfg.ShutdownGracePeriod)
+		defer timer.Stop()
+
+		select {
+		case <-done:
+		case <-timer.C:
+			log.Println("Grace period expired, aborting current cycle")
+			cancel()
+		}
+	}()
+
+	performSynchronization(cycleCtx, r, p, w, exchange)
+}
+
+func performSynchronization(ctx context.Context, r *reader.Reader, p *processor.Processor, w *writer.Writer, exchange string) {
 	start := time.Now()
 	log.Printf("[%s] Starting synchronization check...", start.Format("15:04:05"))
 
-	r := reader.NewReader()
-	p := processor.NewProcessor()
-	w := writer.NewWriter(cfg.TelegramBotToken, cfg.TelegramChatID)
-
 	var fetchedSymbols []models.Symbol
+	var report *models.FetchReport
 	var err error
 
 	if exchange != "" {
-		fetchedSymbols, err = r.FetchSymbolsByExchange(exchange)
+		fetchedSymbols, report, err = r.FetchSymbolsByExchange(ctx, exchange)
 	} else {
-		fetchedSymbols, err = r.FetchAllSymbols()
+		fetchedSymbols, report, err = r.FetchAllSymbols(ctx)
+	}
+
+	if writeErr := w.WriteFetchReport(ctx, report); writeErr != nil {
+		log.Printf("Error saving fetch report: %v", writeErr)
 	}
 
 	if err != nil {
@@ -135,22 +258,28 @@ func performSynchronization(exchange string, cfg *config.Config) {
 		return
 	}
 
-	newSymbols, err := p.ProcessSymbols(fetchedSymbols)
+	for _, result := range report.Failed() {
+		log.Printf("[%s] Fetch failed for %s %s after %v: %s",
+			start.Format("15:04:05"), result.Exchange, result.Market, result.Latency, result.ErrorMessage)
+	}
+
+	changes, err := p.ProcessSymbols(ctx, fetchedSymbols, report)
 	if err != nil {
 		log.Printf("Error processing symbols: %v", err)
 		return
 	}
 
-	if err := w.ProcessAndWrite(newSymbols); err != nil {
+	if err := w.ProcessAndWrite(ctx, changes); err != nil {
 		log.Printf("Error writing symbols: %v", err)
 		return
 	}
 
-	if len(newSymbols) > 0 {
-		log.Printf("[%s] Found %d new symbols out of %d total (took %v)",
-			start.Format("15:04:05"), len(newSymbols), len(fetchedSymbols), time.Since(start))
+	if changes.HasChanges() {
+		log.Printf("[%s] Found %d new, %d delisted and %d relisted symbols out of %d total (took %v)",
+			start.Format("15:04:05"), len(changes.New), len(changes.Delisted), len(changes.Relisted),
+			len(fetchedSymbols), time.Since(start))
 
-		if err := w.SendSummaryToTelegram(len(fetchedSymbols), len(newSymbols)); err != nil {
+		if err := w.SendSummary(ctx, len(fetchedSymbols), changes, report); err != nil {
 			log.Printf("Error sending summary: %v", err)
 		}
 	} else {
@@ -159,18 +288,132 @@ func performSynchronization(exchange string, cfg *config.Config) {
 	}
 }
 
+func newReader(ctx context.Context, symbolStore store.SymbolStore, cfg *config.Config) *reader.Reader {
+	exs, err := exchanges.DefaultRegistry.Build(cfg.EnabledExchanges, cfg.DisabledExchanges)
+	if err != nil {
+		log.Fatalf("Invalid exchange configuration: %v", err)
+	}
+	seedOptionCoins(ctx, symbolStore, exs)
+	return reader.NewReader(exs)
+}
+
+// seedOptionCoins passes the underlyings of the stored active options to the
+// exchanges that discover them at runtime.
+func seedOptionCoins(ctx context.Context, symbolStore store.SymbolStore, exs []exchanges.ExchangeInterface) {
+	for _, ex := range exs {
+		seeder, ok := ex.(exchanges.OptionCoinSeeder)
+		if !ok {
+			continue
+		}
+
+		stored, err := symbolStore.ListByExchangeAndType(ctx, ex.GetName(), models.MarketOption)
+		if err != nil {
+			log.Printf("Error loading stored %s options: %v", ex.GetName(), err)
+			continue
+		}
+
+		seen := make(map[string]bool)
+		var coins []string
+		for _, symbol := range stored {
+			coin := symbol.BaseAsset
+			if coin == "" {
+				coin, _, _ = strings.Cut(symbol.Symbol, "-")
+			}
+			if symbol.IsDelisted() || seen[coin] {
+				continue
+			}
+			seen[coin] = true
+			coins = append(coins, coin)
+		}
+		seeder.SeedOptionCoins(coins)
+	}
+}
+
+func newProcessor(symbolStore store.SymbolStore, cfg *config.Config) *processor.Processor {
+	p := processor.NewProcessor(symbolStore)
+	p.SetDelistGuard(processor.DelistGuard{
+		DefaultThreshold:   cfg.DelistGuardThreshold,
+		ExchangeThresholds: cfg.DelistGuardThresholds,
+		MinStoredCount:     cfg.DelistGuardMinCount,
+		ConfirmAfter:       cfg.DelistGuardConfirmAfter,
+	})
+	return p
+}
+
+// newNotifiers enables every notification sink that is configured.
+func newNotifiers(cfg *config.Config) []notifier.Notifier {
+	var notifiers []notifier.Notifier
+	if cfg.TelegramBotToken != "" && cfg.TelegramChatID != "" {
+		notifiers = append(notifiers, notifier.NewTelegram(cfg.TelegramBotToken, cfg.TelegramChatID))
+	}
+	if cfg.DiscordWebhookURL != "" {
+		notifiers = append(notifiers, notifier.NewDiscord(cfg.DiscordWebhookURL))
+	}
+	if cfg.SlackWebhookURL != "" {
+		notifiers = append(notifiers, notifier.NewSlack(cfg.SlackWebhookURL))
+	}
+	if cfg.WebhookURL != "" {
+		notifiers = append(notifiers, notifier.NewWebhook(cfg.WebhookURL, cfg.WebhookSecret))
+	}
+	if cfg.FeishuWebhookURL != "" {
+		notifiers = append(notifiers, notifier.NewFeishu(cfg.FeishuWebhookURL, cfg.FeishuSecret))
+	}
+	if cfg.DingTalkWebhookURL != "" {
+		notifiers = append(notifiers, notifier.NewDingTalk(cfg.DingTalkWebhookURL, cfg.DingTalkSecret, cfg.DingTalkKeyword))
+	}
+	if cfg.WeComWebhookURL != "" {
+		notifiers = append(notifiers, notifier.NewWeCom(cfg.WeComWebhookURL))
+	}
+	if cfg.SMTPHost != "" {
+		smtpConfig := notifier.SMTPConfig{
+			Host:     cfg.SMTPHost,
+			Port:     cfg.SMTPPort,
+			Username: cfg.SMTPUsername,
+			Password: cfg.SMTPPassword,
+			From:     cfg.SMTPFrom,
+			Security: cfg.SMTPSecurity,
+		}
+		if len(cfg.EmailTo) == 0 && len(cfg.EmailToExchanges) == 0 {
+			log.Println("Warning: SMTP_HOST is set but EMAIL_TO and EMAIL_TO_EXCHANGES are empty, email disabled")
+		} else if err := smtpConfig.Validate(); err != nil {
+			log.Printf("Warning: %v, email disabled", err)
+		} else {
+			email := notifier.NewEmail(smtpConfig, cfg.EmailTo, cfg.EmailToExchanges)
+			switch cfg.EmailDigest {
+			case "":
+			case "daily":
+				email.SetDailyDigest(cfg.EmailDigestTime)
+			default:
+				log.Printf("Warning: unknown EMAIL_DIGEST=%q, sending emails immediately", cfg.EmailDigest)
+			}
+			notifiers = append(notifiers, email)
+		}
+	}
+
+	names := make([]string, 0, len(notifiers))
+	for _, n := range notifiers {
+		names = append(names, n.Name())
+	}
+	if len(names) > 0 {
+		log.Printf("Notifications enabled: %s", strings.Join(names, ", "))
+	}
+	return notifiers
+}
+
 func showHelp() {
-	log.Println(`
+	log.Print(`
 Exchange Symbol Synchronizer
 
 Usage:
   go run main.go [options]
 
 Options:
-  -exchange string    Fetch symbols from specific exchange (binance, okx, gate, bitget, bybit)
+  -exchange string    Fetch symbols from specific exchange (see Available Exchanges)
   -stats              Show database statistics
   -verify             Compare API data with database data for detailed verification
-  -daemon             Run in daemon mode with 5-second periodic checks
+  -lookup string      List every exchange that trades a canonical symbol (BASE/QUOTE[:SETTLE])
+  -daemon             Run in daemon mode with periodic checks (DAEMON_INTERVAL)
+  -backfill-listed-at Fill listed_at of stored symbols from exchange listing times
   -help               Show this help message
 
 Examples:
@@ -179,30 +422,80 @@ Examples:
   go run main.go -stats                 # Show database statistics
   go run main.go -verify                # Verify API vs database for all exchanges
   go run main.go -verify -exchange binance # Verify API vs database for Binance only
-  go run main.go -daemon                # Run daemon mode checking every 5 seconds
+  go run main.go -lookup BTC/USDT:USDT  # Show BTC USDT-margined perpetuals on all exchanges
+  go run main.go -daemon                # Run daemon mode checking every DAEMON_INTERVAL
+  go run main.go -backfill-listed-at    # Fill listed_at of symbols stored before it existed
   go run main.go -daemon -exchange binance # Run daemon mode for Binance only
 
 Environment Variables:
   TELEGRAM_BOT_TOKEN    Your Telegram bot token
   TELEGRAM_CHAT_ID      Your Telegram chat ID
-  DATABASE_PATH         Database file path (default: symbols.db)
+  DISCORD_WEBHOOK_URL   Discord channel webhook for alerts
+  SLACK_WEBHOOK_URL     Slack incoming webhook for alerts
+  WEBHOOK_URL           Generic endpoint that receives every alert as JSON
+  WEBHOOK_SECRET        HMAC-SHA256 key signing WEBHOOK_URL requests (X-Signature-256)
+  FEISHU_WEBHOOK_URL    Feishu/Lark custom bot webhook (FEISHU_SECRET enables signing)
+  DINGTALK_WEBHOOK_URL  DingTalk robot webhook (DINGTALK_SECRET signs, DINGTALK_KEYWORD
+                        is added to every message for keyword-secured robots)
+  WECOM_WEBHOOK_URL     WeCom group robot webhook
+  SMTP_HOST             SMTP server for email alerts of new and delisted symbols
+  SMTP_PORT             SMTP port (default: 587)
+  SMTP_USERNAME         SMTP login, also the sender unless SMTP_FROM is set
+  SMTP_PASSWORD         SMTP password
+  SMTP_FROM             Sender address
+  SMTP_SECURITY         starttls, tls or none (default: tls on port 465, else starttls)
+  EMAIL_TO              Comma separated default recipients
+  EMAIL_TO_EXCHANGES    Per-exchange recipients, e.g. binance:a@example.com|b@example.com,okx:c@example.com
+  EMAIL_DIGEST          Set to daily to send one digest mail a day instead of one per change
+  EMAIL_DIGEST_TIME     Local time of the daily digest (default: 09:00)
+  DB_DRIVER             Storage driver: mysql or sqlite (default: mysql)
+  DATABASE_PATH         SQLite database file path (default: symbols.db)
+  MYSQL_HOST            MySQL host (default: localhost)
+  MYSQL_PORT            MySQL port (default: 3306)
+  MYSQL_USER            MySQL user (default: root)
+  MYSQL_PASSWORD        MySQL password
+  MYSQL_DATABASE        MySQL database name (default: exchange_symbols)
   LOG_LEVEL             Log level (default: info)
+  SYNC_TIMEOUT          Deadline for one synchronization cycle (default: 2m)
+  DAEMON_INTERVAL       Time between daemon cycle starts (default: 5s)
+  DAEMON_JITTER         Random extra delay added to each interval (default: 1s)
+  SHUTDOWN_GRACE_PERIOD Time the current cycle gets to finish on SIGINT/SIGTERM (default: 30s)
+  NOTIFICATION_RETENTION How long sent and failed notifications are kept,
+                           0 keeps them forever (default: 168h)
+  DELIST_GUARD_THRESHOLD   Max fraction of a market that may vanish in one poll
+                           before delistings are held back (default: 0.2)
+  DELIST_GUARD_THRESHOLDS  Per-exchange overrides, e.g. gate:0.3,okx:0.1
+  DELIST_GUARD_MIN_COUNT   Markets with fewer stored symbols are only held back
+                           when the list is empty (default: 20)
+  DELIST_GUARD_CONFIRM_AFTER How long a drop is held before it is applied,
+                           0 holds it until the count recovers (default: 1h)
+  ENABLED_EXCHANGES     Comma separated exchanges to poll (default: all)
+  DISABLED_EXCHANGES    Comma separated exchanges to skip
 `)
+
+	log.Println("Available Exchanges:")
+	for _, name := range exchanges.DefaultRegistry.Names() {
+		registration, _ := exchanges.DefaultRegistry.Get(name)
+		markets := make([]string, 0, len(registration.Markets))
+		for _, market := range registration.Markets {
+			markets = append(markets, string(market))
+		}
+		log.Printf("  %-10s markets: %s", name, strings.Join(markets, ", "))
+	}
 }
 
-func showStats() {
-	p := processor.NewProcessor()
+func showStats(ctx context.Context, symbolStore store.SymbolStore) {
+	p := processor.NewProcessor(symbolStore)
 
-	total, err := p.GetSymbolCount()
+	total, err := p.GetSymbolCount(ctx)
 	if err != nil {
 		log.Fatalf("Error getting total count: %v", err)
 	}
 
 	log.Printf("Total symbols in database: %d", total)
 
-	exchanges := []string{"binance", "okx", "gate", "bitget", "bybit"}
-	for _, exchange := range exchanges {
-		count, err := p.GetSymbolCountByExchange(exchange)
+	for _, exchange := range exchanges.DefaultRegistry.Names() {
+		count, err := p.GetSymbolCountByExchange(ctx, exchange)
 		if err != nil {
 			log.Printf("Error getting count for %s: %v", exchange, err)
 			continue
@@ -210,42 +503,89 @@ func showStats() {
 		log.Printf("  %s: %d symbols", exchange, count)
 	}
 
-	spotCount, err := p.GetExistingSymbolsByType("spot")
+	for _, market := range models.AllMarketTypes {
+		symbols, err := p.GetExistingSymbolsByType(ctx, market)
+		if err != nil {
+			log.Printf("Error getting %s count: %v", market, err)
+			continue
+		}
+		if len(symbols) > 0 {
+			log.Printf("%s symbols: %d", market.Label(), len(symbols))
+		}
+	}
+
+	results, err := symbolStore.ListFetchResults(ctx)
 	if err != nil {
-		log.Printf("Error getting spot count: %v", err)
-	} else {
-		log.Printf("Spot symbols: %d", len(spotCount))
+		log.Printf("Error getting last fetch status: %v", err)
+		return
+	}
+
+	log.Println("Last fetch status:")
+	for _, result := range results {
+		if result.OK() {
+			log.Printf("  %s %s: ok, %d symbols in %v (%s)", result.Exchange, result.Market,
+				result.SymbolCount, result.Latency, result.FetchedAt.Format("2006-01-02 15:04:05"))
+		} else {
+			log.Printf("  %s %s: failed after %v (%s): %s", result.Exchange, result.Market,
+				result.Latency, result.FetchedAt.Format("2006-01-02 15:04:05"), result.ErrorMessage)
+		}
+		if result.HeldPolls > 0 && result.HeldSince != nil {
+			log.Printf("    delistings held back by the delist guard since %s (%d polls)",
+				result.HeldSince.Format("2006-01-02 15:04:05"), result.HeldPolls)
+		}
 	}
+}
 
-	futuresCount, err := p.GetExistingSymbolsByType("futures")
+func showCanonicalLookup(ctx context.Context, symbolStore store.SymbolStore, canonical string) {
+	p := processor.NewProcessor(symbolStore)
+
+	symbols, err := p.GetSymbolsByCanonical(ctx, canonical)
 	if err != nil {
-		log.Printf("Error getting futures count: %v", err)
-	} else {
-		log.Printf("Futures symbols: %d", len(futuresCount))
+		log.Fatalf("Error looking up %s: %v", canonical, err)
+	}
+
+	log.Printf("%s is listed on %d markets:", strings.ToUpper(canonical), len(symbols))
+	for _, symbol := range symbols {
+		log.Printf("  %-8s %-18s %-20s %s", symbol.Exchange, symbol.Type, symbol.Symbol, symbol.Status)
+	}
+}
+
+func logFetchReport(report *models.FetchReport) {
+	for _, result := range report.Results {
+		if result.OK() {
+			log.Printf("  %s %s: ok, %d symbols in %v", result.Exchange, result.Market, result.SymbolCount, result.Latency)
+		} else {
+			log.Printf("  %s %s: failed after %v: %s", result.Exchange, result.Market, result.Latency, result.ErrorMessage)
+		}
 	}
 }
 
-func showDataVerification(exchange string) {
+func showDataVerification(ctx context.Context, symbolStore store.SymbolStore, exchange string, cfg *config.Config) {
 	log.Println("=== 开始API与数据库数据验证 ===")
 
-	r := reader.NewReader()
-	p := processor.NewProcessor()
+	r := newReader(ctx, symbolStore, cfg)
+	p := processor.NewProcessor(symbolStore)
 
 	start := time.Now()
 
-	var exchanges []string
+	var exchangeNames []string
 	if exchange != "" {
-		exchanges = []string{exchange}
+		exchangeNames = []string{exchange}
 		log.Printf("验证交易所: %s", exchange)
 	} else {
-		exchanges = []string{"binance", "okx", "gate", "bitget", "bybit"}
+		exchangeNames = r.ExchangeNames()
 		log.Println("验证所有交易所")
 	}
 
-	for _, ex := range exchanges {
+	for _, ex := range exchangeNames {
+		if ctx.Err() != nil {
+			log.Printf("验证已中断: %v", ctx.Err())
+			break
+		}
+
 		log.Printf("\n=== 开始验证 %s 交易所数据 ===", ex)
 
-		fetchedSymbols, err := r.FetchSymbolsByExchange(ex)
+		fetchedSymbols, report, err := r.FetchSymbolsByExchange(ctx, ex)
 		if err != nil {
 			log.Printf("获取%s交易所数据失败: %v", ex, err)
 			continue
@@ -256,28 +596,23 @@ func showDataVerification(exchange string) {
 			continue
 		}
 
-		var spotSymbols, futuresSymbols []models.Symbol
+		byMarket := make(map[models.MarketType][]models.Symbol)
 		for _, symbol := range fetchedSymbols {
-			if symbol.Type == "spot" {
-				spotSymbols = append(spotSymbols, symbol)
-			} else if symbol.Type == "futures" {
-				futuresSymbols = append(futuresSymbols, symbol)
-			}
+			byMarket[symbol.Type] = append(byMarket[symbol.Type], symbol)
 		}
 
-		if len(spotSymbols) > 0 {
-			log.Printf("\n--- 验证 %s 现货数据 ---", ex)
-			_, err := p.CompareAPIWithDatabase(spotSymbols, ex, "spot")
-			if err != nil {
-				log.Printf("现货数据对比失败: %v", err)
+		for _, result := range report.Results {
+			if !result.OK() {
+				log.Printf("%s %s 数据获取失败，跳过验证", ex, result.Market.Label())
+				continue
+			}
+			if len(byMarket[result.Market]) == 0 {
+				continue
 			}
-		}
 
-		if len(futuresSymbols) > 0 {
-			log.Printf("\n--- 验证 %s 合约数据 ---", ex)
-			_, err := p.CompareAPIWithDatabase(futuresSymbols, ex, "futures")
-			if err != nil {
-				log.Printf("合约数据对比失败: %v", err)
+			log.Printf("\n--- 验证 %s %s 数据 ---", ex, result.Market.Label())
+			if _, err := p.CompareAPIWithDatabase(ctx, byMarket[result.Market], ex, result.Market); err != nil {
+				log.Printf("%s 数据对比失败: %v", result.Market.Label(), err)
 			}
 		}
 
@@ -286,3 +621,40 @@ func showDataVerification(exchange string) {
 
 	log.Printf("\n=== 全部验证完成，耗时: %v ===", time.Since(start))
 }
+
+// backfillListedAt fetches the current instruments and stores their
+// exchange-provided listing times on rows created before listed_at existed.
+func backfillListedAt(ctx context.Context, symbolStore store.SymbolStore, exchange string, cfg *config.Config) {
+	ctx, cancel := context.WithTimeout(ctx, cfg.SyncTimeout)
+	defer cancel()
+
+	r := newReader(ctx, symbolStore, cfg)
+
+	var fetchedSymbols []models.Symbol
+	var report *models.FetchReport
+	var err error
+	if exchange != "" {
+		fetchedSymbols, report, err = r.FetchSymbolsByExchange(ctx, exchange)
+	} else {
+		fetchedSymbols, report, err = r.FetchAllSymbols(ctx)
+	}
+	if err != nil {
+		log.Fatalf("Error fetching symbols: %v", err)
+	}
+	logFetchReport(report)
+
+	withListingTime := 0
+	for _, symbol := range fetchedSymbols {
+		if symbol.ListedAt != nil {
+			withListingTime++
+		}
+	}
+
+	updated, err := symbolStore.UpdateListedAt(ctx, fetchedSymbols)
+	if err != nil {
+		log.Fatalf("Error backfilling listed_at: %v", err)
+	}
+
+	log.Printf("%d of %d fetched symbols report a listing time, updated listed_at of %d stored symbols",
+		withListingTime, len(fetchedSymbols), updated)
+}
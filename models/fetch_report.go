@@ -0,0 +1,74 @@
+package models
+
+import "time"
+
+const (
+	FetchStatusOK     = "ok"
+	FetchStatusFailed = "failed"
+)
+
+// MarketFetchResult records the outcome of fetching one exchange+market. The
+// latest result per market is persisted so -stats can show it.
+type MarketFetchResult struct {
+	ID           uint          `gorm:"primaryKey" json:"id"`
+	Exchange     string        `gorm:"size:32;not null;uniqueIndex:idx_exchange_market" json:"exchange"`
+	Market       MarketType    `gorm:"size:32;not null;uniqueIndex:idx_exchange_market" json:"market"`
+	Status       string        `gorm:"not null" json:"status"` // "ok" or "failed"
+	ErrorMessage string        `gorm:"type:text" json:"error_message"`
+	SymbolCount  int           `json:"symbol_count"`
+	Latency      time.Duration `json:"latency"`
+	FetchedAt    time.Time     `json:"fetched_at"`
+	HeldPolls    int           `gorm:"not null;default:0" json:"held_polls"` // consecutive polls held back by the delist guard
+	HeldSince    *time.Time    `json:"held_since"`                           // first poll of the current hold
+	Err          error         `gorm:"-" json:"-"`
+}
+
+func (r *MarketFetchResult) OK() bool {
+	return r.Status == FetchStatusOK
+}
+
+// FetchReport collects per exchange+market results of one fetch run.
+type FetchReport struct {
+	Results []MarketFetchResult
+}
+
+func (r *FetchReport) Add(exchange string, market MarketType, symbolCount int, latency time.Duration, err error) {
+	result := MarketFetchResult{
+		Exchange:    exchange,
+		Market:      market,
+		Status:      FetchStatusOK,
+		SymbolCount: symbolCount,
+		Latency:     latency,
+		FetchedAt:   time.Now(),
+	}
+	if err != nil {
+		result.Status = FetchStatusFailed
+		result.ErrorMessage = err.Error()
+		result.Err = err
+	}
+	r.Results = append(r.Results, result)
+}
+
+// IsOK reports whether exchange+market was fetched successfully. Markets that
+// are not part of the report were not fetched and are not OK.
+func (r *FetchReport) IsOK(exchange string, market MarketType) bool {
+	if r == nil {
+		return false
+	}
+	for _, result := range r.Results {
+		if result.Exchange == exchange && result.Market == market {
+			return result.OK()
+		}
+	}
+	return false
+}
+
+func (r *FetchReport) Failed() []MarketFetchResult {
+	var failed []MarketFetchResult
+	for _, result := range r.Results {
+		if !result.OK() {
+			failed = append(failed, result)
+		}
+	}
+	return failed
+}
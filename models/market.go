@@ -0,0 +1,74 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+)
+
+// MarketType classifies an instrument. It is stored in Symbol.Type and is
+// part of the exchange-type-symbol combination.
+type MarketType string
+
+const (
+	MarketSpot             MarketType = "spot"
+	MarketMargin           MarketType = "margin"
+	MarketLinearPerpetual  MarketType = "linear_perpetual"
+	MarketInversePerpetual MarketType = "inverse_perpetual"
+	MarketLinearDelivery   MarketType = "linear_delivery"
+	MarketInverseDelivery  MarketType = "inverse_delivery"
+	MarketOption           MarketType = "option"
+)
+
+// LegacyFuturesType is the Type value used before the market taxonomy
+// existed; rows with it are reclassified on startup.
+const LegacyFuturesType MarketType = "futures"
+
+var AllMarketTypes = []MarketType{
+	MarketSpot,
+	MarketMargin,
+	MarketLinearPerpetual,
+	MarketInversePerpetual,
+	MarketLinearDelivery,
+	MarketInverseDelivery,
+	MarketOption,
+}
+
+var marketLabels = map[MarketType]string{
+	MarketSpot:             "Spot",
+	MarketMargin:           "Margin",
+	MarketLinearPerpetual:  "Linear Perpetual",
+	MarketInversePerpetual: "Inverse Perpetual",
+	MarketLinearDelivery:   "Linear Delivery",
+	MarketInverseDelivery:  "Inverse Delivery",
+	MarketOption:           "Option",
+}
+
+func (m MarketType) Label() string {
+	if label, ok := marketLabels[m]; ok {
+		return label
+	}
+	return string(m)
+}
+
+func (m MarketType) IsDerivative() bool {
+	return m != MarketSpot && m != MarketMargin
+}
+
+// IsInverse reports whether contracts of this market are margined and
+// settled in the base coin.
+func (m MarketType) IsInverse() bool {
+	return m == MarketInversePerpetual || m == MarketInverseDelivery
+}
+
+// IsDelivery reports whether contracts of this market have an expiry.
+func (m MarketType) IsDelivery() bool {
+	return m == MarketLinearDelivery || m == MarketInverseDelivery
+}
+
+func ParseMarketType(value string) (MarketType, error) {
+	market := MarketType(strings.ToLower(strings.TrimSpace(value)))
+	if _, ok := marketLabels[market]; ok {
+		return market, nil
+	}
+	return "", fmt.Errorf("unknown market type %q", value)
+}
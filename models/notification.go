@@ -0,0 +1,42 @@
+package models
+
+import "time"
+
+const (
+	NotificationPending = "pending"
+	NotificationSent    = "sent"
+	NotificationFailed  = "failed" // rejected by the platform, never retried
+)
+
+// Notification is one rendered message in the delivery outbox of a
+// notifier channel. Rows are written in the same transaction as the changes
+// they announce and each channel delivers its rows in ID order, so an alert
+// survives restarts and outages of that platform.
+type Notification struct {
+	ID            uint       `gorm:"primaryKey" json:"id"`
+	Channel       string     `gorm:"size:32;not null;default:telegram;index" json:"channel"` // notifier name, e.g. "telegram"
+	Status        string     `gorm:"size:16;not null;default:pending;index" json:"status"`   // "pending", "sent" or "failed"
+	Text          string     `gorm:"type:mediumtext;not null" json:"text"`                   // digests and webhook payloads can exceed TEXT
+	Attempts      int        `gorm:"not null;default:0" json:"attempts"`
+	NextAttemptAt time.Time  `json:"next_attempt_at"`
+	LastError     string     `gorm:"type:text" json:"last_error"`
+	SentAt        *time.Time `json:"sent_at"`
+	CreatedAt     time.Time  `gorm:"index" json:"created_at"`
+}
+
+// NewNotifications queues each message for channel as a pending
+// notification, due now.
+func NewNotifications(channel string, messages []string) []Notification {
+	now := time.Now()
+	notifications := make([]Notification, 0, len(messages))
+	for _, message := range messages {
+		notifications = append(notifications, Notification{
+			Channel:       channel,
+			Status:        NotificationPending,
+			Text:          message,
+			NextAttemptAt: now,
+			CreatedAt:     now,
+		})
+	}
+	return notifications
+}
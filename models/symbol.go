@@ -6,16 +6,133 @@ import (
 	"gorm.io/gorm"
 )
 
+const (
+	StatusActive   = "active"
+	StatusDelisted = "delisted"
+)
+
 type Symbol struct {
-	ID          uint      `gorm:"primaryKey" json:"id"`
-	Exchange    string    `gorm:"not null;index" json:"exchange"`
-	Type        string    `gorm:"not null;index" json:"type"` // "spot" or "futures"
-	Symbol      string    `gorm:"not null;index" json:"symbol"`
-	Combination string    `gorm:"not null;unique" json:"combination"` // exchange-type-symbol
-	CreatedAt   time.Time `json:"created_at"`
+	ID             uint       `gorm:"primaryKey" json:"id"`
+	Exchange       string     `gorm:"not null;index" json:"exchange"`
+	Type           MarketType `gorm:"not null;index" json:"type"`
+	Symbol         string     `gorm:"not null;index" json:"symbol"`
+	Combination    string     `gorm:"not null;unique" json:"combination"`          // exchange-type-symbol
+	Status         string     `gorm:"not null;default:active;index" json:"status"` // "active" or "delisted"
+	DelistedAt     *time.Time `json:"delisted_at"`
+	DeliveryAt     *time.Time `json:"delivery_at"` // expiry of delivery contracts, nil otherwise
+	ListedAt       *time.Time `json:"listed_at"`   // listing time reported by the exchange, if any
+	InstrumentInfo `gorm:"embedded"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+// InstrumentInfo is the trading metadata reported by the exchange plus the
+// canonical identifier derived from it. Numeric values are kept as the
+// exchange's decimal strings to avoid float rounding.
+type InstrumentInfo struct {
+	Canonical         string        `gorm:"size:64;index" json:"canonical"` // BASE/QUOTE[:SETTLE], see normalizer
+	BaseAsset         string        `gorm:"size:32;index" json:"base_asset"`
+	QuoteAsset        string        `gorm:"size:32;index" json:"quote_asset"`
+	SettleAsset       string        `gorm:"size:32" json:"settle_asset"`
+	ExchangeStatus    string        `gorm:"size:32" json:"exchange_status"`      // raw status string from the exchange
+	TradingStatus     TradingStatus `gorm:"size:16;index" json:"trading_status"` // normalized ExchangeStatus
+	TickSize          string        `gorm:"size:64" json:"tick_size"`
+	LotSize           string        `gorm:"size:64" json:"lot_size"`
+	MinQty            string        `gorm:"size:64" json:"min_qty"`
+	MinNotional       string        `gorm:"size:64" json:"min_notional"`
+	PricePrecision    int           `json:"price_precision"`
+	QuantityPrecision int           `json:"quantity_precision"`
+	ContractSize      string        `gorm:"size:64" json:"contract_size"`
+	ContractType      string        `gorm:"size:32" json:"contract_type"` // raw contract type, e.g. CURRENT_QUARTER
+	// DelistingScheduled is set while the exchange has announced a delisting
+	// but still lists the instrument.
+	DelistingScheduled bool `gorm:"not null;default:false" json:"delisting_scheduled"`
+}
+
+// BuildCombination returns the exchange-type-symbol key used for uniqueness.
+func BuildCombination(exchange string, symbolType MarketType, symbol string) string {
+	return exchange + "-" + string(symbolType) + "-" + symbol
+}
+
+func (s *Symbol) Key() string {
+	return BuildCombination(s.Exchange, s.Type, s.Symbol)
+}
+
+func (s *Symbol) IsDelisted() bool {
+	return s.Status == StatusDelisted
 }
 
 func (s *Symbol) BeforeCreate(tx *gorm.DB) error {
-	s.Combination = s.Exchange + "-" + s.Type + "-" + s.Symbol
+	s.Combination = s.Key()
+	if s.Status == "" {
+		s.Status = StatusActive
+	}
 	return nil
 }
+
+// MetadataChanged reports whether the exchange-provided metadata differs.
+func (s *Symbol) MetadataChanged(other *Symbol) bool {
+	return s.InstrumentInfo != other.InstrumentInfo || !sameTime(s.DeliveryAt, other.DeliveryAt)
+}
+
+func sameTime(a, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Equal(*b)
+}
+
+// GuardWarning describes a market whose fetched symbol count dropped by more
+// than the configured threshold, so its delistings were held back.
+type GuardWarning struct {
+	Exchange     string
+	Market       MarketType
+	StoredCount  int
+	FetchedCount int
+	DropRatio    float64
+	Threshold    float64
+	HeldPolls    int       // consecutive polls held back, this one included
+	HeldSince    time.Time // first poll of the current hold
+}
+
+// FirstPoll reports whether the market was just held back, as opposed to
+// still being held from an earlier poll.
+func (w GuardWarning) FirstPoll() bool {
+	return w.HeldPolls == 1
+}
+
+// GuardState is how long the delist guard has held back the delistings of
+// one market: the consecutive polls and the time of the first one. Both are
+// reset once it is released.
+type GuardState struct {
+	Exchange  string
+	Market    MarketType
+	HeldPolls int
+	HeldSince *time.Time
+}
+
+// SymbolChanges is the outcome of comparing fetched symbols with the store.
+type SymbolChanges struct {
+	New         []Symbol
+	Delisted    []Symbol
+	Expired     []Symbol // delivery contracts and options gone after expiry, delisted without an alert
+	Relisted    []Symbol
+	Updated     []Symbol // existing symbols whose metadata changed
+	Transitions []StatusTransition
+	Warnings    []GuardWarning
+	GuardStates []GuardState // markets whose held poll count changed
+}
+
+func (c *SymbolChanges) HasChanges() bool {
+	return len(c.New) > 0 || len(c.Delisted) > 0 || len(c.Relisted) > 0 || len(c.Transitions) > 0
+}
+
+// TransitionsByEvent returns the symbols of every transition with event.
+func (c *SymbolChanges) TransitionsByEvent(event StatusEvent) []Symbol {
+	var symbols []Symbol
+	for _, transition := range c.Transitions {
+		if transition.Event == event {
+			symbols = append(symbols, transition.Symbol)
+		}
+	}
+	return symbols
+}
@@ -0,0 +1,73 @@
+package models
+
+// TradingStatus is the exchange status normalized across exchanges. The raw
+// value stays in InstrumentInfo.ExchangeStatus.
+type TradingStatus string
+
+const (
+	TradingUnknown    TradingStatus = ""
+	TradingPreListing TradingStatus = "pre_trading"
+	TradingLive       TradingStatus = "trading"
+	TradingHalted     TradingStatus = "halted"
+	TradingDelisting  TradingStatus = "delisting"
+)
+
+// StatusEvent names a trading status transition worth alerting on.
+type StatusEvent string
+
+const (
+	EventPreListed StatusEvent = "pre_listed"
+	EventWentLive  StatusEvent = "went_live"
+	EventHalted    StatusEvent = "halted"
+	EventResumed   StatusEvent = "resumed"
+	EventDelisting StatusEvent = "delisting"
+)
+
+// AllStatusEvents is the order events are reported in.
+var AllStatusEvents = []StatusEvent{
+	EventPreListed,
+	EventWentLive,
+	EventHalted,
+	EventResumed,
+	EventDelisting,
+}
+
+// StatusTransition is one symbol whose trading status changed in this poll.
+// From is TradingUnknown for symbols seen for the first time.
+type StatusTransition struct {
+	Symbol Symbol
+	From   TradingStatus
+	To     TradingStatus
+	Event  StatusEvent
+}
+
+// TransitionEvent returns the event for a status change, if any. Changes from
+// an unknown status are ignored except for new symbols that are not trading
+// yet, so rows stored before statuses were tracked do not raise alerts.
+func TransitionEvent(from, to TradingStatus, isNew bool) (StatusEvent, bool) {
+	if from == to || to == TradingUnknown {
+		return "", false
+	}
+
+	if isNew {
+		if to == TradingPreListing {
+			return EventPreListed, true
+		}
+		return "", false
+	}
+
+	switch {
+	case from == TradingUnknown:
+		return "", false
+	case to == TradingDelisting:
+		return EventDelisting, true
+	case from == TradingPreListing && to == TradingLive:
+		return EventWentLive, true
+	case from == TradingHalted && to == TradingLive:
+		return EventResumed, true
+	case from == TradingLive && to == TradingHalted:
+		return EventHalted, true
+	default:
+		return "", false
+	}
+}
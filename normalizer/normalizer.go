@@ -0,0 +1,178 @@
+package normalizer
+
+import (
+	"all_exchange_symbol/models"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// knownQuotes is used to split raw symbols without a separator such as
+// BTCUSDT. Longer codes come first so FDUSD wins over USD.
+var knownQuotes = []string{
+	"FDUSD", "USDT", "USDC", "BUSD", "TUSD", "USDE", "DAI",
+	"EUR", "TRY", "BRL", "JPY", "GBP", "AUD",
+	"BTC", "ETH", "BNB", "USD",
+}
+
+// exchangeSuffixes are stripped from raw symbols before parsing.
+var exchangeSuffixes = []string{"_UMCBL", "_DMCBL", "_CMCBL", "_SPBL", "-SWAP", "_PERP", "-PERP"}
+
+var (
+	multiplierPrefix = regexp.MustCompile(`^(10000000|1000000|100000|10000|1000|100|1M)([A-Z].*)$`)
+	// Bybit appends the multiplier to some bases instead, e.g. SHIB1000
+	multiplierSuffix = regexp.MustCompile(`^([A-Z][A-Z0-9]*[A-Z])(1000000|100000|10000|1000)$`)
+	expirySuffix     = regexp.MustCompile(`[-_](\d{8}|\d{6})$`)
+	// Bybit dates its linear delivery contracts DMMMYY, e.g. BTCUSDT-28MAR25
+	monthExpirySuffix = regexp.MustCompile(`-(\d{1,2}[A-Z]{3}\d{2})$`)
+	// options are dated YYMMDD (OKX), YYYYMMDD (Gate) or DMMMYY (Bybit, e.g.
+	// 28MAR25); Bybit appends the settle coin to USDT-settled series
+	optionSuffix = regexp.MustCompile(`[-_](\d{8}|\d{6}|\d{1,2}[A-Z]{3}\d{2})[-_](\d+(?:\.\d+)?)[-_]([CP])(?:-[A-Z]+)?$`)
+)
+
+// Canonical returns the cross-exchange identifier of a symbol in the form
+// BASE/QUOTE for spot and BASE/QUOTE:SETTLE for derivatives, with a
+// -YYMMDD suffix for dated contracts and -YYMMDD-STRIKE-C|P for options.
+// Multipliers such as 1000PEPE, 1MBABYDOGE and SHIB1000 are removed from
+// derivatives so they line up with the underlying; spot tokens like
+// 1000SATS and leveraged tokens like BTC3L keep their name because that is
+// the asset itself.
+func Canonical(symbol *models.Symbol) string {
+	raw := strings.ToUpper(symbol.Symbol)
+	base := strings.ToUpper(symbol.BaseAsset)
+	quote := strings.ToUpper(symbol.QuoteAsset)
+	settle := strings.ToUpper(symbol.SettleAsset)
+
+	expiry := ""
+	if m := optionSuffix.FindStringSubmatch(raw); m != nil {
+		expiry = expiryDate(m[1]) + "-" + m[2] + "-" + m[3]
+		raw = strings.TrimSuffix(raw, m[0])
+	} else if m := expirySuffix.FindStringSubmatch(raw); m != nil {
+		expiry = expiryDate(m[1])
+		raw = strings.TrimSuffix(raw, m[0])
+	} else if m := monthExpirySuffix.FindStringSubmatch(raw); m != nil && expiryDate(m[1]) != m[1] {
+		expiry = expiryDate(m[1])
+		raw = strings.TrimSuffix(raw, m[0])
+	} else if symbol.Type.IsDelivery() && symbol.DeliveryAt != nil {
+		// codes like Bybit's BTCUSDH25 carry no parseable date
+		expiry = symbol.DeliveryAt.UTC().Format("060102")
+	}
+
+	if base == "" || quote == "" {
+		base, quote = splitRaw(raw)
+	}
+	if base == "" || quote == "" {
+		return ""
+	}
+
+	if !symbol.Type.IsDerivative() {
+		return base + "/" + quote
+	}
+
+	base = StripMultiplier(base)
+	if settle == "" {
+		settle = quote
+		if quote == "USD" {
+			// USD-quoted contracts are coin-margined
+			settle = base
+		}
+	}
+
+	canonical := base + "/" + quote + ":" + settle
+	if expiry != "" {
+		canonical += "-" + expiry
+	}
+	return canonical
+}
+
+// expiryDate converts a YYYYMMDD or DMMMYY date to YYMMDD; YYMMDD is
+// returned as is.
+func expiryDate(date string) string {
+	if len(date) == 8 && !strings.ContainsAny(date, "ABCDEFGHIJKLMNOPQRSTUVWXYZ") {
+		return date[2:]
+	}
+	if len(date) == 6 && !strings.ContainsAny(date, "ABCDEFGHIJKLMNOPQRSTUVWXYZ") {
+		return date
+	}
+	t, err := time.Parse("2Jan06", date)
+	if err != nil {
+		return date
+	}
+	return t.Format("060102")
+}
+
+// StripMultiplier removes a contract multiplier: 1000PEPE -> PEPE,
+// 1MBABYDOGE -> BABYDOGE, SHIB1000 -> SHIB.
+func StripMultiplier(base string) string {
+	if m := multiplierPrefix.FindStringSubmatch(base); m != nil {
+		return m[2]
+	}
+	if m := multiplierSuffix.FindStringSubmatch(base); m != nil {
+		return m[1]
+	}
+	return base
+}
+
+func splitRaw(raw string) (string, string) {
+	for _, suffix := range exchangeSuffixes {
+		raw = strings.TrimSuffix(raw, suffix)
+	}
+
+	for _, sep := range []string{"/", "-", "_"} {
+		if parts := strings.Split(raw, sep); len(parts) >= 2 && parts[0] != "" && parts[1] != "" {
+			return parts[0], parts[1]
+		}
+	}
+
+	for _, quote := range knownQuotes {
+		if strings.HasSuffix(raw, quote) && len(raw) > len(quote) {
+			return strings.TrimSuffix(raw, quote), quote
+		}
+	}
+
+	return "", ""
+}
+
+// ClassifyContract infers the market type of a contract stored before the
+// taxonomy existed: dated symbols (YYMMDD, YYYYMMDD or Bybit's DMMMYY) are
+// delivery contracts and contracts settled in the base coin (or quoted in
+// USD) are inverse.
+func ClassifyContract(symbol *models.Symbol) models.MarketType {
+	raw := strings.ToUpper(symbol.Symbol)
+	base := strings.ToUpper(symbol.BaseAsset)
+	quote := strings.ToUpper(symbol.QuoteAsset)
+	settle := strings.ToUpper(symbol.SettleAsset)
+
+	delivery := false
+	if m := expirySuffix.FindStringSubmatch(raw); m != nil {
+		delivery = true
+		raw = strings.TrimSuffix(raw, m[0])
+	} else if m := monthExpirySuffix.FindStringSubmatch(raw); m != nil && expiryDate(m[1]) != m[1] {
+		// expiryDate leaves anything that is not a real date unchanged
+		delivery = true
+		raw = strings.TrimSuffix(raw, m[0])
+	}
+	if base == "" || quote == "" {
+		base, quote = splitRaw(raw)
+	}
+
+	inverse := quote == "USD" || (settle != "" && settle == base)
+	switch {
+	case delivery && inverse:
+		return models.MarketInverseDelivery
+	case delivery:
+		return models.MarketLinearDelivery
+	case inverse:
+		return models.MarketInversePerpetual
+	default:
+		return models.MarketLinearPerpetual
+	}
+}
+
+// Apply fills Canonical and TradingStatus on every symbol in place.
+func Apply(symbols []models.Symbol) {
+	for i := range symbols {
+		symbols[i].Canonical = Canonical(&symbols[i])
+		symbols[i].TradingStatus = TradingStatus(&symbols[i])
+	}
+}
@@ -0,0 +1,109 @@
+package normalizer
+
+import (
+	"all_exchange_symbol/models"
+	"testing"
+	"time"
+)
+
+// TestClassifyContractLegacyNames covers the symbol names each exchange
+// stored under the legacy "futures" type, with and without the asset
+// columns filled in.
+func TestClassifyContractLegacyNames(t *testing.T) {
+	tests := []struct {
+		exchange string
+		symbol   models.Symbol
+		want     models.MarketType
+	}{
+		{"binance", models.Symbol{Symbol: "BTCUSDT"}, models.MarketLinearPerpetual},
+		{"binance", models.Symbol{Symbol: "1000PEPEUSDT"}, models.MarketLinearPerpetual},
+		{"binance", models.Symbol{Symbol: "BTCUSDT_250328"}, models.MarketLinearDelivery},
+		{"binance", models.Symbol{Symbol: "ETHUSDC"}, models.MarketLinearPerpetual},
+
+		{"okx", models.Symbol{Symbol: "BTC-USDT-SWAP"}, models.MarketLinearPerpetual},
+		{"okx", models.Symbol{Symbol: "BTC-USD-SWAP"}, models.MarketInversePerpetual},
+		{"okx", models.Symbol{Symbol: "BTC-USD-SWAP", InstrumentInfo: models.InstrumentInfo{
+			BaseAsset: "BTC", QuoteAsset: "USD", SettleAsset: "BTC"}}, models.MarketInversePerpetual},
+
+		{"bybit", models.Symbol{Symbol: "BTCUSDT"}, models.MarketLinearPerpetual},
+		{"bybit", models.Symbol{Symbol: "BTCPERP"}, models.MarketLinearPerpetual},
+		{"bybit", models.Symbol{Symbol: "BTC-28MAR25"}, models.MarketLinearDelivery},
+		{"bybit", models.Symbol{Symbol: "BTCUSDT-28MAR25"}, models.MarketLinearDelivery},
+		{"bybit", models.Symbol{Symbol: "ETH-3JAN25"}, models.MarketLinearDelivery},
+		{"bybit", models.Symbol{Symbol: "BTCUSDT-28MAR25", InstrumentInfo: models.InstrumentInfo{
+			BaseAsset: "BTC", QuoteAsset: "USDT", SettleAsset: "USDT"}}, models.MarketLinearDelivery},
+		{"bybit", models.Symbol{Symbol: "BTC-28XYZ25"}, models.MarketLinearPerpetual},
+
+		{"bitget", models.Symbol{Symbol: "BTCUSDT_UMCBL"}, models.MarketLinearPerpetual},
+		{"bitget", models.Symbol{Symbol: "BTCUSD_DMCBL"}, models.MarketInversePerpetual},
+
+		{"gate", models.Symbol{Symbol: "BTC_USDT"}, models.MarketLinearPerpetual},
+		{"gate", models.Symbol{Symbol: "BTC_USDT_20250328"}, models.MarketLinearDelivery},
+	}
+
+	for _, tt := range tests {
+		symbol := tt.symbol
+		symbol.Exchange = tt.exchange
+		symbol.Type = models.LegacyFuturesType
+		if got := ClassifyContract(&symbol); got != tt.want {
+			t.Errorf("ClassifyContract(%s %s) = %s, want %s", tt.exchange, tt.symbol.Symbol, got, tt.want)
+		}
+	}
+}
+
+func TestCanonical(t *testing.T) {
+	delivery := time.Date(2025, 3, 28, 8, 0, 0, 0, time.UTC)
+	assets := func(base, quote, settle string) models.InstrumentInfo {
+		return models.InstrumentInfo{BaseAsset: base, QuoteAsset: quote, SettleAsset: settle}
+	}
+
+	tests := []struct {
+		exchange string
+		symbol   models.Symbol
+		want     string
+	}{
+		{"binance", models.Symbol{Type: models.MarketSpot, Symbol: "BTCUSDT", InstrumentInfo: assets("BTC", "USDT", "")}, "BTC/USDT"},
+		{"binance", models.Symbol{Type: models.MarketSpot, Symbol: "1000SATSUSDT", InstrumentInfo: assets("1000SATS", "USDT", "")}, "1000SATS/USDT"},
+		{"binance", models.Symbol{Type: models.MarketLinearPerpetual, Symbol: "BTCUSDT", InstrumentInfo: assets("BTC", "USDT", "USDT")}, "BTC/USDT:USDT"},
+		{"binance", models.Symbol{Type: models.MarketLinearPerpetual, Symbol: "1000PEPEUSDT", InstrumentInfo: assets("1000PEPE", "USDT", "USDT")}, "PEPE/USDT:USDT"},
+		{"binance", models.Symbol{Type: models.MarketLinearPerpetual, Symbol: "1MBABYDOGEUSDT", InstrumentInfo: assets("1MBABYDOGE", "USDT", "USDT")}, "BABYDOGE/USDT:USDT"},
+		{"binance", models.Symbol{Type: models.MarketLinearDelivery, Symbol: "BTCUSDT_250328", InstrumentInfo: assets("BTC", "USDT", "USDT")}, "BTC/USDT:USDT-250328"},
+		{"binance", models.Symbol{Type: models.MarketInversePerpetual, Symbol: "BTCUSD_PERP", InstrumentInfo: assets("BTC", "USD", "BTC")}, "BTC/USD:BTC"},
+		{"binance", models.Symbol{Type: models.MarketInverseDelivery, Symbol: "BTCUSD_250328", InstrumentInfo: assets("BTC", "USD", "BTC")}, "BTC/USD:BTC-250328"},
+
+		{"okx", models.Symbol{Type: models.MarketSpot, Symbol: "BTC-USDT", InstrumentInfo: assets("BTC", "USDT", "")}, "BTC/USDT"},
+		{"okx", models.Symbol{Type: models.MarketLinearPerpetual, Symbol: "BTC-USDT-SWAP", InstrumentInfo: assets("BTC", "USDT", "USDT")}, "BTC/USDT:USDT"},
+		{"okx", models.Symbol{Type: models.MarketInversePerpetual, Symbol: "BTC-USD-SWAP", InstrumentInfo: assets("BTC", "USD", "BTC")}, "BTC/USD:BTC"},
+		{"okx", models.Symbol{Type: models.MarketInverseDelivery, Symbol: "BTC-USD-250328", InstrumentInfo: assets("BTC", "USD", "BTC")}, "BTC/USD:BTC-250328"},
+		{"okx", models.Symbol{Type: models.MarketOption, Symbol: "BTC-USD-250328-80000-C", InstrumentInfo: assets("BTC", "USD", "BTC")}, "BTC/USD:BTC-250328-80000-C"},
+
+		{"bybit", models.Symbol{Type: models.MarketSpot, Symbol: "BTCUSDT", InstrumentInfo: assets("BTC", "USDT", "")}, "BTC/USDT"},
+		{"bybit", models.Symbol{Type: models.MarketLinearPerpetual, Symbol: "SHIB1000USDT", InstrumentInfo: assets("SHIB1000", "USDT", "USDT")}, "SHIB/USDT:USDT"},
+		{"bybit", models.Symbol{Type: models.MarketLinearPerpetual, Symbol: "10000000AIDOGEUSDT", InstrumentInfo: assets("10000000AIDOGE", "USDT", "USDT")}, "AIDOGE/USDT:USDT"},
+		{"bybit", models.Symbol{Type: models.MarketLinearDelivery, Symbol: "BTCUSDT-28MAR25", InstrumentInfo: assets("BTC", "USDT", "USDT")}, "BTC/USDT:USDT-250328"},
+		{"bybit", models.Symbol{Type: models.MarketInverseDelivery, Symbol: "BTCUSDH25", DeliveryAt: &delivery, InstrumentInfo: assets("BTC", "USD", "BTC")}, "BTC/USD:BTC-250328"},
+		{"bybit", models.Symbol{Type: models.MarketOption, Symbol: "BTC-28MAR25-80000-C", InstrumentInfo: assets("BTC", "USD", "USDC")}, "BTC/USD:USDC-250328-80000-C"},
+		{"bybit", models.Symbol{Type: models.MarketOption, Symbol: "ETH-3JAN25-3500-P-USDT", InstrumentInfo: assets("ETH", "USDT", "USDT")}, "ETH/USDT:USDT-250103-3500-P"},
+
+		{"bitget", models.Symbol{Type: models.MarketSpot, Symbol: "BTCUSDT", InstrumentInfo: assets("BTC", "USDT", "")}, "BTC/USDT"},
+		{"bitget", models.Symbol{Type: models.MarketLinearPerpetual, Symbol: "BTCUSDT", InstrumentInfo: assets("BTC", "USDT", "USDT")}, "BTC/USDT:USDT"},
+		{"bitget", models.Symbol{Type: models.MarketInversePerpetual, Symbol: "BTCUSD", InstrumentInfo: assets("BTC", "USD", "BTC")}, "BTC/USD:BTC"},
+		{"bitget", models.Symbol{Type: models.MarketLinearPerpetual, Symbol: "BTCUSDT_UMCBL"}, "BTC/USDT:USDT"},
+		{"bitget", models.Symbol{Type: models.MarketInversePerpetual, Symbol: "BTCUSD_DMCBL"}, "BTC/USD:BTC"},
+
+		{"gate", models.Symbol{Type: models.MarketSpot, Symbol: "BTC_USDT", InstrumentInfo: assets("BTC", "USDT", "")}, "BTC/USDT"},
+		{"gate", models.Symbol{Type: models.MarketLinearPerpetual, Symbol: "BTC_USDT", InstrumentInfo: assets("BTC", "USDT", "USDT")}, "BTC/USDT:USDT"},
+		{"gate", models.Symbol{Type: models.MarketLinearPerpetual, Symbol: "1000PEPE_USDT"}, "PEPE/USDT:USDT"},
+		{"gate", models.Symbol{Type: models.MarketInversePerpetual, Symbol: "BTC_USD", InstrumentInfo: assets("BTC", "USD", "BTC")}, "BTC/USD:BTC"},
+		{"gate", models.Symbol{Type: models.MarketLinearDelivery, Symbol: "BTC_USDT_20250328", InstrumentInfo: assets("BTC", "USDT", "USDT")}, "BTC/USDT:USDT-250328"},
+		{"gate", models.Symbol{Type: models.MarketOption, Symbol: "BTC_USDT-20250328-80000-C", InstrumentInfo: assets("BTC", "USDT", "USDT")}, "BTC/USDT:USDT-250328-80000-C"},
+	}
+
+	for _, tt := range tests {
+		symbol := tt.symbol
+		symbol.Exchange = tt.exchange
+		if got := Canonical(&symbol); got != tt.want {
+			t.Errorf("Canonical(%s %s %s) = %q, want %q", tt.exchange, symbol.Type, symbol.Symbol, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package normalizer
+
+import (
+	"all_exchange_symbol/models"
+	"strings"
+)
+
+// tradingStatuses maps each exchange's raw status, lowercased, to a
+// normalized status. Values not listed are reported as unknown.
+var tradingStatuses = map[string]map[string]models.TradingStatus{
+	"binance": {
+		"trading":         models.TradingLive,
+		"pending_trading": models.TradingPreListing,
+		"pre_trading":     models.TradingPreListing,
+		"break":           models.TradingHalted,
+		"halt":            models.TradingHalted,
+		"auction_match":   models.TradingHalted,
+		"post_trading":    models.TradingHalted,
+		"end_of_day":      models.TradingHalted,
+		"close":           models.TradingHalted,
+		"pre_delivering":  models.TradingDelisting,
+		"delivering":      models.TradingDelisting,
+		"delivered":       models.TradingDelisting,
+		"pre_settle":      models.TradingDelisting,
+		"settling":        models.TradingDelisting,
+	},
+	"okx": {
+		"live":    models.TradingLive,
+		"preopen": models.TradingPreListing,
+		"test":    models.TradingPreListing,
+		"suspend": models.TradingHalted,
+	},
+	"gate": {
+		"tradable":        models.TradingLive,
+		"trading":         models.TradingLive,
+		"untradable":      models.TradingHalted,
+		"buyable":         models.TradingHalted,
+		"sellable":        models.TradingHalted,
+		"circuit_breaker": models.TradingHalted,
+		"delisting":       models.TradingDelisting,
+		"delisted":        models.TradingDelisting,
+	},
+	"bitget": {
+		"online":        models.TradingLive,
+		"normal":        models.TradingLive,
+		"limit_open":    models.TradingLive,
+		"gray":          models.TradingPreListing,
+		"listed":        models.TradingPreListing,
+		"offline":       models.TradingHalted,
+		"halt":          models.TradingHalted,
+		"maintain":      models.TradingHalted,
+		"restrictedapi": models.TradingHalted,
+		"off":           models.TradingDelisting,
+	},
+	"bybit": {
+		"trading":    models.TradingLive,
+		"prelaunch":  models.TradingPreListing,
+		"settling":   models.TradingDelisting,
+		"delivering": models.TradingDelisting,
+		"closed":     models.TradingDelisting,
+	},
+}
+
+// TradingStatus normalizes the exchange status of a symbol. An announced
+// delisting wins over the raw status, which usually still reads trading.
+func TradingStatus(symbol *models.Symbol) models.TradingStatus {
+	if symbol.DelistingScheduled {
+		return models.TradingDelisting
+	}
+	return tradingStatuses[symbol.Exchange][strings.ToLower(symbol.ExchangeStatus)]
+}
@@ -0,0 +1,77 @@
+package notifier
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// discordMaxLength is the content limit of a webhook message in characters.
+const discordMaxLength = 2000
+
+var discordEscaper = strings.NewReplacer(`\`, `\\`, "*", `\*`, "_", `\_`, "~", `\~`, "`", "\\`", "|", `\|`, ">", `\>`)
+
+var discordMarkup = markup{
+	escape: discordEscaper.Replace,
+	bold:   func(text string) string { return "**" + text + "**" },
+	code:   func(text string) string { return "`" + strings.ReplaceAll(text, "`", "'") + "`" },
+	length: utf8.RuneCountInString,
+}
+
+// Discord posts Markdown messages to a channel webhook.
+type Discord struct {
+	webhookURL string
+	client     *http.Client
+}
+
+type discordMessage struct {
+	Content         string `json:"content"`
+	AllowedMentions struct {
+		Parse []string `json:"parse"`
+	} `json:"allowed_mentions"`
+}
+
+func NewDiscord(webhookURL string) *Discord {
+	return &Discord{
+		webhookURL: webhookURL,
+		client:     newHTTPClient(),
+	}
+}
+
+func (d *Discord) Name() string {
+	return "discord"
+}
+
+func (d *Discord) Format(alert Alert) []string {
+	return discordMarkup.messages(alert, discordMaxLength)
+}
+
+// Send posts one message with mentions disabled, so a symbol or error text
+// can never ping @everyone.
+func (d *Discord) Send(ctx context.Context, message string) error {
+	payload := discordMessage{Content: message}
+	payload.AllowedMentions.Parse = []string{}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	data, err := post(ctx, d.client, d.Name(), d.webhookURL, body, nil)
+
+	var apiErr *Error
+	if errors.As(err, &apiErr) && apiErr.RetryAfter == 0 {
+		// 429 bodies carry retry_after in fractional seconds
+		var response struct {
+			RetryAfter float64 `json:"retry_after"`
+		}
+		if json.Unmarshal(data, &response) == nil && response.RetryAfter > 0 {
+			apiErr.RetryAfter = time.Duration(response.RetryAfter * float64(time.Second))
+		}
+	}
+	return err
+}
@@ -0,0 +1,514 @@
+package notifier
+
+import (
+	"all_exchange_symbol/models"
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html/template"
+	"log"
+	"math/rand"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/http"
+	"net/smtp"
+	"net/textproto"
+	"sort"
+	"strings"
+	"time"
+)
+
+// SMTP connection security modes.
+const (
+	SMTPStartTLS = "starttls" // plain connection upgraded with STARTTLS, usually port 587
+	SMTPTLS      = "tls"      // implicit TLS, usually port 465
+	SMTPNone     = "none"     // unencrypted, for a local relay only
+)
+
+// smtpTimeout bounds one delivery when the context has no deadline.
+const smtpTimeout = time.Minute
+
+type SMTPConfig struct {
+	Host     string
+	Port     string
+	Username string
+	Password string
+	From     string
+	Security string // SMTPStartTLS, SMTPTLS or SMTPNone
+}
+
+// Email sends new and delisted symbols as HTML and plain text mails, grouped
+// by exchange and market type. Each exchange can have its own recipients;
+// exchanges without any use the default ones. In daily digest mode every
+// mail is held back until the digest time and merged with everything else
+// detected since the previous digest.
+type Email struct {
+	smtp               SMTPConfig
+	recipients         []string
+	exchangeRecipients map[string][]string
+
+	digest   bool
+	digestAt time.Duration // local time of day
+}
+
+// emailEnvelope is what the outbox stores per mail: the recipients and the
+// symbols of one alert. The mail itself is rendered at send time, so a
+// digest can merge several envelopes.
+type emailEnvelope struct {
+	To         []string      `json:"to"`
+	Kind       AlertKind     `json:"kind"`
+	Symbols    []emailSymbol `json:"symbols"`
+	DetectedAt time.Time     `json:"detected_at"`
+}
+
+type emailSymbol struct {
+	Exchange  string            `json:"exchange"`
+	Market    models.MarketType `json:"market"`
+	Symbol    string            `json:"symbol"`
+	Canonical string            `json:"canonical,omitempty"`
+}
+
+// emailSections are the alert kinds that are mailed, in mail order. Symbols
+// listed before trading opens are left out of the new symbols alert, so they
+// are mailed as new when they go live.
+var emailSections = []struct {
+	Kinds []AlertKind
+	Title string
+}{
+	{[]AlertKind{AlertNewSymbols, AlertKind(models.EventWentLive)}, "New symbols"},
+	{[]AlertKind{AlertDelisted}, "Delisted symbols"},
+}
+
+// Validate rejects settings that can never deliver a mail: net/smtp only
+// sends a password over TLS or to localhost, so a login with SMTPNone
+// needs a local relay.
+func (c SMTPConfig) Validate() error {
+	switch c.Security {
+	case "", SMTPStartTLS, SMTPTLS:
+		return nil
+	case SMTPNone:
+		if c.Username != "" && !isLocalhost(c.Host) {
+			return fmt.Errorf("SMTP_SECURITY=none with SMTP_USERNAME only works with a relay on localhost, not %s", c.Host)
+		}
+		return nil
+	default:
+		return fmt.Errorf("unknown SMTP_SECURITY %q", c.Security)
+	}
+}
+
+func isLocalhost(host string) bool {
+	return host == "localhost" || host == "127.0.0.1" || host == "::1"
+}
+
+func NewEmail(config SMTPConfig, recipients []string, exchangeRecipients map[string][]string) *Email {
+	if config.Security == "" {
+		config.Security = SMTPStartTLS
+		if config.Port == "465" {
+			config.Security = SMTPTLS
+		}
+	}
+	if config.From == "" {
+		config.From = config.Username
+	}
+
+	return &Email{
+		smtp:               config,
+		recipients:         recipients,
+		exchangeRecipients: exchangeRecipients,
+	}
+}
+
+// SetDailyDigest switches to one digest mail per recipient list a day, sent
+// at the given local time of day.
+func (e *Email) SetDailyDigest(at time.Duration) {
+	e.digest = true
+	e.digestAt = at
+}
+
+func (e *Email) Name() string {
+	return "email"
+}
+
+// NextSend holds messages back until the next digest time in digest mode.
+func (e *Email) NextSend(queuedAt time.Time) time.Time {
+	if !e.digest {
+		return queuedAt
+	}
+
+	year, month, day := queuedAt.Date()
+	next := time.Date(year, month, day, 0, 0, 0, 0, queuedAt.Location()).Add(e.digestAt)
+	if !next.After(queuedAt) {
+		next = time.Date(year, month, day+1, 0, 0, 0, 0, queuedAt.Location()).Add(e.digestAt)
+	}
+	return next
+}
+
+// Format routes the symbols of new, went live and delisted alerts to their
+// recipients, one envelope per recipient list. Other alerts are not mailed.
+func (e *Email) Format(alert Alert) []string {
+	if !isMailed(alert.Kind) {
+		return nil
+	}
+
+	byRecipients := make(map[string]*emailEnvelope)
+	var keys []string
+	for _, symbol := range alert.Symbols {
+		to := e.recipientsFor(symbol.Exchange)
+		if len(to) == 0 {
+			continue
+		}
+
+		key := strings.Join(to, ",")
+		envelope, ok := byRecipients[key]
+		if !ok {
+			envelope = &emailEnvelope{To: to, Kind: alert.Kind, DetectedAt: time.Now()}
+			byRecipients[key] = envelope
+			keys = append(keys, key)
+		}
+		envelope.Symbols = append(envelope.Symbols, emailSymbol{
+			Exchange:  symbol.Exchange,
+			Market:    symbol.Type,
+			Symbol:    symbol.Symbol,
+			Canonical: symbol.Canonical,
+		})
+	}
+	sort.Strings(keys)
+
+	var messages []string
+	for _, key := range keys {
+		data, err := json.Marshal(byRecipients[key])
+		if err != nil {
+			log.Printf("Error encoding email for %s: %v", alert.Kind, err)
+			continue
+		}
+		messages = append(messages, string(data))
+	}
+	return messages
+}
+
+func isMailed(kind AlertKind) bool {
+	for _, section := range emailSections {
+		if hasKind(section.Kinds, kind) {
+			return true
+		}
+	}
+	return false
+}
+
+func hasKind(kinds []AlertKind, kind AlertKind) bool {
+	for _, k := range kinds {
+		if k == kind {
+			return true
+		}
+	}
+	return false
+}
+
+func (e *Email) recipientsFor(exchange string) []string {
+	if recipients := e.exchangeRecipients[exchange]; len(recipients) > 0 {
+		return recipients
+	}
+	return e.recipients
+}
+
+func (e *Email) Send(ctx context.Context, message string) error {
+	return e.SendBatch(ctx, []string{message})[0]
+}
+
+// SendBatch sends one mail per recipient list, merging all of its envelopes.
+func (e *Email) SendBatch(ctx context.Context, messages []string) []error {
+	errs := make([]error, len(messages))
+
+	envelopes := make(map[string][]emailEnvelope)
+	indexes := make(map[string][]int)
+	var keys []string
+	for i, message := range messages {
+		var envelope emailEnvelope
+		if err := json.Unmarshal([]byte(message), &envelope); err != nil || len(envelope.To) == 0 {
+			errs[i] = &Error{Notifier: e.Name(), StatusCode: http.StatusBadRequest, Description: "malformed email envelope"}
+			continue
+		}
+
+		key := strings.Join(envelope.To, ",")
+		if _, ok := envelopes[key]; !ok {
+			keys = append(keys, key)
+		}
+		envelopes[key] = append(envelopes[key], envelope)
+		indexes[key] = append(indexes[key], i)
+	}
+
+	for _, key := range keys {
+		err := e.sendMail(ctx, envelopes[key])
+		for _, i := range indexes[key] {
+			errs[i] = err
+		}
+	}
+	return errs
+}
+
+// emailSection and emailContent feed the HTML template and the plain text
+// part.
+type emailSection struct {
+	Title     string
+	Count     int
+	Exchanges []exchangeGroup
+}
+
+type emailContent struct {
+	Subject  string
+	Period   string
+	Sections []emailSection
+}
+
+var emailHTML = template.Must(template.New("email").Parse(`<!DOCTYPE html>
+<html>
+<body style="font-family: -apple-system, Segoe UI, Helvetica, Arial, sans-serif; color: #222;">
+<h2>{{.Subject}}</h2>
+{{if .Period}}<p style="color: #666;">{{.Period}}</p>{{end}}
+{{range .Sections}}<h3>{{.Title}} ({{.Count}})</h3>
+{{range .Exchanges}}<h4 style="margin-bottom: 4px;">{{.Exchange}}</h4>
+{{range .Markets}}<p style="margin: 4px 0 0 16px;">{{.Market.Label}}: {{len .Symbols}} symbols</p>
+<ul style="margin-top: 2px;">
+{{range .Symbols}}<li><code>{{.Symbol}}</code>{{if .Canonical}} <span style="color: #666;">{{.Canonical}}</span>{{end}}</li>
+{{end}}</ul>
+{{end}}{{end}}{{end}}
+</body>
+</html>
+`))
+
+func (e *Email) content(envelopes []emailEnvelope) emailContent {
+	first, last := envelopes[0].DetectedAt, envelopes[0].DetectedAt
+	for _, envelope := range envelopes {
+		if envelope.DetectedAt.Before(first) {
+			first = envelope.DetectedAt
+		}
+		if envelope.DetectedAt.After(last) {
+			last = envelope.DetectedAt
+		}
+	}
+
+	var content emailContent
+	var counts []string
+	for _, section := range emailSections {
+		var symbols []models.Symbol
+		for _, envelope := range envelopes {
+			if !hasKind(section.Kinds, envelope.Kind) {
+				continue
+			}
+			for _, symbol := range envelope.Symbols {
+				symbols = append(symbols, models.Symbol{
+					Exchange:       symbol.Exchange,
+					Type:           symbol.Market,
+					Symbol:         symbol.Symbol,
+					InstrumentInfo: models.InstrumentInfo{Canonical: symbol.Canonical},
+				})
+			}
+		}
+		if len(symbols) == 0 {
+			continue
+		}
+
+		content.Sections = append(content.Sections, emailSection{
+			Title:     section.Title,
+			Count:     len(symbols),
+			Exchanges: groupSymbols(symbols),
+		})
+		counts = append(counts, fmt.Sprintf("%d %s", len(symbols), strings.ToLower(section.Title)))
+	}
+
+	summary := strings.Join(counts, ", ")
+	if e.digest {
+		content.Subject = fmt.Sprintf("Daily symbol digest %s: %s", time.Now().Format("2006-01-02"), summary)
+		content.Period = fmt.Sprintf("Detected between %s and %s",
+			first.Local().Format("2006-01-02 15:04"), last.Local().Format("2006-01-02 15:04"))
+	} else {
+		content.Subject = "Symbol tracker: " + summary
+	}
+	return content
+}
+
+func (c emailContent) text() string {
+	var b strings.Builder
+	b.WriteString(c.Subject + "\n")
+	if c.Period != "" {
+		b.WriteString(c.Period + "\n")
+	}
+	for _, section := range c.Sections {
+		fmt.Fprintf(&b, "\n%s (%d)\n", section.Title, section.Count)
+		for _, group := range section.Exchanges {
+			fmt.Fprintf(&b, "\n%s:\n", group.Exchange)
+			for _, market := range group.Markets {
+				fmt.Fprintf(&b, "  %s: %d symbols\n", market.Market.Label(), len(market.Symbols))
+				for _, symbol := range market.Symbols {
+					if symbol.Canonical != "" {
+						fmt.Fprintf(&b, "    - %s (%s)\n", symbol.Symbol, symbol.Canonical)
+					} else {
+						fmt.Fprintf(&b, "    - %s\n", symbol.Symbol)
+					}
+				}
+			}
+		}
+	}
+	return b.String()
+}
+
+func (e *Email) sendMail(ctx context.Context, envelopes []emailEnvelope) error {
+	content := e.content(envelopes)
+	if len(content.Sections) == 0 {
+		return nil
+	}
+
+	var htmlBody bytes.Buffer
+	if err := emailHTML.Execute(&htmlBody, content); err != nil {
+		return &Error{Notifier: e.Name(), StatusCode: http.StatusBadRequest, Description: err.Error()}
+	}
+
+	message, err := e.buildMessage(envelopes[0].To, content.Subject, content.text(), htmlBody.String())
+	if err != nil {
+		return err
+	}
+
+	return e.deliver(ctx, envelopes[0].To, message)
+}
+
+// buildMessage assembles a multipart/alternative mail with quoted-printable
+// plain text and HTML parts.
+func (e *Email) buildMessage(to []string, subject, text, htmlBody string) ([]byte, error) {
+	var body bytes.Buffer
+	parts := multipart.NewWriter(&body)
+	for _, alternative := range []struct{ contentType, content string }{
+		{"text/plain; charset=UTF-8", text},
+		{"text/html; charset=UTF-8", htmlBody},
+	} {
+		part, err := parts.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {alternative.contentType},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		if err != nil {
+			return nil, err
+		}
+		encoder := quotedprintable.NewWriter(part)
+		if _, err := encoder.Write([]byte(alternative.content)); err != nil {
+			return nil, err
+		}
+		if err := encoder.Close(); err != nil {
+			return nil, err
+		}
+	}
+	if err := parts.Close(); err != nil {
+		return nil, err
+	}
+
+	domain := "localhost"
+	if at := strings.LastIndex(e.smtp.From, "@"); at >= 0 {
+		domain = e.smtp.From[at+1:]
+	}
+
+	var message bytes.Buffer
+	fmt.Fprintf(&message, "From: %s\r\n", e.smtp.From)
+	fmt.Fprintf(&message, "To: %s\r\n", strings.Join(to, ", "))
+	fmt.Fprintf(&message, "Subject: %s\r\n", mime.QEncoding.Encode("UTF-8", subject))
+	fmt.Fprintf(&message, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	fmt.Fprintf(&message, "Message-ID: <%d.%d@%s>\r\n", time.Now().UnixNano(), rand.Int63(), domain)
+	message.WriteString("MIME-Version: 1.0\r\n")
+	fmt.Fprintf(&message, "Content-Type: multipart/alternative; boundary=%q\r\n\r\n", parts.Boundary())
+	message.Write(body.Bytes())
+
+	return message.Bytes(), nil
+}
+
+// deliver runs one SMTP session. 5xx replies, such as a rejected login or
+// recipient, are permanent; everything else is retried.
+func (e *Email) deliver(ctx context.Context, to []string, message []byte) error {
+	address := net.JoinHostPort(e.smtp.Host, e.smtp.Port)
+	tlsConfig := &tls.Config{ServerName: e.smtp.Host}
+	dialer := &net.Dialer{Timeout: 10 * time.Second}
+
+	var conn net.Conn
+	var err error
+	if e.smtp.Security == SMTPTLS {
+		conn, err = (&tls.Dialer{NetDialer: dialer, Config: tlsConfig}).DialContext(ctx, "tcp", address)
+	} else {
+		conn, err = dialer.DialContext(ctx, "tcp", address)
+	}
+	if err != nil {
+		return err
+	}
+
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = time.Now().Add(smtpTimeout)
+	}
+	conn.SetDeadline(deadline)
+
+	client, err := smtp.NewClient(conn, e.smtp.Host)
+	if err != nil {
+		conn.Close()
+		return e.smtpError(err)
+	}
+	defer client.Close()
+
+	if e.smtp.Security == SMTPStartTLS {
+		if ok, _ := client.Extension("STARTTLS"); !ok {
+			return &Error{Notifier: e.Name(), StatusCode: http.StatusBadRequest, Description: e.smtp.Host + " does not offer STARTTLS"}
+		}
+		if err := client.StartTLS(tlsConfig); err != nil {
+			return e.smtpError(err)
+		}
+	}
+
+	if e.smtp.Username != "" {
+		if err := client.Auth(smtp.PlainAuth("", e.smtp.Username, e.smtp.Password, e.smtp.Host)); err != nil {
+			var replyErr *textproto.Error
+			if !errors.As(err, &replyErr) {
+				// PlainAuth refused to send the password, e.g. over an
+				// unencrypted connection; retrying cannot fix that
+				return &Error{Notifier: e.Name(), StatusCode: http.StatusBadRequest, Description: "smtp auth: " + err.Error()}
+			}
+			return e.smtpError(err)
+		}
+	}
+
+	if err := client.Mail(e.smtp.From); err != nil {
+		return e.smtpError(err)
+	}
+	for _, recipient := range to {
+		if err := client.Rcpt(recipient); err != nil {
+			return e.smtpError(err)
+		}
+	}
+
+	data, err := client.Data()
+	if err != nil {
+		return e.smtpError(err)
+	}
+	if _, err := data.Write(message); err != nil {
+		return e.smtpError(err)
+	}
+	if err := data.Close(); err != nil {
+		return e.smtpError(err)
+	}
+
+	// the server accepted the mail; a failed QUIT must not send it again
+	if err := client.Quit(); err != nil {
+		log.Printf("Error closing SMTP session with %s: %v", e.smtp.Host, err)
+	}
+	return nil
+}
+
+func (e *Email) smtpError(err error) error {
+	var replyErr *textproto.Error
+	if errors.As(err, &replyErr) && replyErr.Code >= 500 {
+		return &Error{
+			Notifier:    e.Name(),
+			StatusCode:  http.StatusBadRequest,
+			Description: fmt.Sprintf("smtp %d %s", replyErr.Code, replyErr.Msg),
+		}
+	}
+	return err
+}
@@ -0,0 +1,55 @@
+package notifier
+
+import (
+	"all_exchange_symbol/models"
+	"encoding/json"
+	"testing"
+)
+
+func TestEmailMailsWentLiveAsNewSymbols(t *testing.T) {
+	email := NewEmail(SMTPConfig{Host: "smtp.example.com"}, []string{"ops@example.com"}, nil)
+
+	messages := email.Format(Alert{
+		Kind:    AlertKind(models.EventWentLive),
+		Symbols: []models.Symbol{{Exchange: "binance", Type: models.MarketSpot, Symbol: "NEWUSDT"}},
+	})
+	if len(messages) != 1 {
+		t.Fatalf("went live alert produced %d mails, want 1", len(messages))
+	}
+
+	var envelope emailEnvelope
+	if err := json.Unmarshal([]byte(messages[0]), &envelope); err != nil {
+		t.Fatalf("decode envelope: %v", err)
+	}
+	content := email.content([]emailEnvelope{envelope})
+	if len(content.Sections) != 1 || content.Sections[0].Title != "New symbols" || content.Sections[0].Count != 1 {
+		t.Errorf("sections %+v, want one New symbols section", content.Sections)
+	}
+
+	if messages := email.Format(Alert{
+		Kind:    AlertKind(models.EventPreListed),
+		Symbols: []models.Symbol{{Exchange: "binance", Type: models.MarketSpot, Symbol: "NEWUSDT"}},
+	}); len(messages) != 0 {
+		t.Errorf("pre-listed alert produced %d mails, want 0", len(messages))
+	}
+}
+
+func TestSMTPConfigValidate(t *testing.T) {
+	tests := []struct {
+		config SMTPConfig
+		valid  bool
+	}{
+		{SMTPConfig{Host: "smtp.example.com", Username: "bot", Security: SMTPStartTLS}, true},
+		{SMTPConfig{Host: "smtp.example.com", Username: "bot", Security: SMTPTLS}, true},
+		{SMTPConfig{Host: "smtp.example.com", Security: SMTPNone}, true},
+		{SMTPConfig{Host: "localhost", Username: "bot", Security: SMTPNone}, true},
+		{SMTPConfig{Host: "smtp.example.com", Username: "bot", Security: SMTPNone}, false},
+		{SMTPConfig{Host: "smtp.example.com", Security: "ssl"}, false},
+	}
+
+	for _, tt := range tests {
+		if err := tt.config.Validate(); (err == nil) != tt.valid {
+			t.Errorf("Validate(%+v) = %v, want valid %v", tt.config, err, tt.valid)
+		}
+	}
+}
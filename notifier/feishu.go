@@ -0,0 +1,154 @@
+package notifier
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// feishuMaxLength keeps the markdown of one card well below the 20 KB
+// request limit of custom bots, leaving room for the JSON around it.
+const feishuMaxLength = 15000
+
+// feishuRateLimited is the business code of a bot that exceeded 100
+// messages per minute or 5 per second.
+const feishuRateLimited = 11232
+
+var feishuEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;", "*", "&#42;", "~", "&#126;")
+
+var feishuMarkup = markup{
+	escape: feishuEscaper.Replace,
+	bold:   func(text string) string { return "**" + text + "**" },
+	code:   feishuEscaper.Replace,
+	length: byteLength,
+}
+
+// feishuTemplates colors the card header by alert kind.
+var feishuTemplates = map[AlertKind]string{
+	AlertNewSymbols: "green",
+	AlertDelisted:   "red",
+	AlertRelisted:   "turquoise",
+	AlertGuard:      "orange",
+	AlertSummary:    "blue",
+}
+
+// Feishu posts interactive cards to a Feishu or Lark custom bot. The webhook
+// host decides which of the two it is (open.feishu.cn or open.larksuite.com).
+type Feishu struct {
+	webhookURL string
+	secret     string
+	client     *http.Client
+}
+
+type feishuCard struct {
+	Config struct {
+		WideScreenMode bool `json:"wide_screen_mode"`
+	} `json:"config"`
+	Header struct {
+		Title struct {
+			Tag     string `json:"tag"`
+			Content string `json:"content"`
+		} `json:"title"`
+		Template string `json:"template"`
+	} `json:"header"`
+	Elements []feishuElement `json:"elements"`
+}
+
+type feishuElement struct {
+	Tag     string `json:"tag"`
+	Content string `json:"content"`
+}
+
+type feishuMessage struct {
+	Timestamp string          `json:"timestamp,omitempty"`
+	Sign      string          `json:"sign,omitempty"`
+	MsgType   string          `json:"msg_type"`
+	Card      json.RawMessage `json:"card"`
+}
+
+func NewFeishu(webhookURL, secret string) *Feishu {
+	return &Feishu{
+		webhookURL: webhookURL,
+		secret:     secret,
+		client:     newHTTPClient(),
+	}
+}
+
+func (f *Feishu) Name() string {
+	return "feishu"
+}
+
+// Format renders one card per part, with the title in the card header. The
+// outbox keeps the card only; Send wraps and signs it at delivery time,
+// because the signature expires after an hour.
+func (f *Feishu) Format(alert Alert) []string {
+	template := feishuTemplates[alert.Kind]
+	if template == "" {
+		template = "blue"
+	}
+
+	var messages []string
+	for _, part := range feishuMarkup.parts(alert, feishuMaxLength) {
+		var card feishuCard
+		card.Config.WideScreenMode = true
+		card.Header.Title.Tag = "plain_text"
+		card.Header.Title.Content = part.Title
+		card.Header.Template = template
+		card.Elements = []feishuElement{{Tag: "markdown", Content: part.Body}}
+
+		data, err := json.Marshal(card)
+		if err != nil {
+			log.Printf("Error encoding feishu card for %s: %v", alert.Kind, err)
+			continue
+		}
+		messages = append(messages, string(data))
+	}
+	return messages
+}
+
+func (f *Feishu) Send(ctx context.Context, message string) error {
+	payload := feishuMessage{MsgType: "interactive", Card: json.RawMessage(message)}
+	if f.secret != "" {
+		payload.Timestamp = strconv.FormatInt(time.Now().Unix(), 10)
+		payload.Sign = feishuSign(f.secret, payload.Timestamp)
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	data, err := post(ctx, f.client, f.Name(), f.webhookURL, body, nil)
+	if err != nil {
+		return err
+	}
+
+	// older bots answer {"StatusCode":0,"StatusMessage":"success"}
+	var response struct {
+		Code          int    `json:"code"`
+		Msg           string `json:"msg"`
+		StatusCode    int    `json:"StatusCode"`
+		StatusMessage string `json:"StatusMessage"`
+	}
+	if err := json.Unmarshal(data, &response); err != nil {
+		return badRobotResponse(f.Name(), data)
+	}
+	if response.Code == 0 && response.StatusCode != 0 {
+		response.Code, response.Msg = response.StatusCode, response.StatusMessage
+	}
+	return robotError(f.Name(), response.Code, response.Msg, response.Code == feishuRateLimited)
+}
+
+// feishuSign is base64(HMAC-SHA256) keyed with timestamp + "\n" + secret
+// over an empty message, as the custom bot signature check expects.
+func feishuSign(secret, timestamp string) string {
+	mac := hmac.New(sha256.New, []byte(timestamp+"\n"+secret))
+	return base64.StdEncoding.EncodeToString(mac.Sum(nil))
+}
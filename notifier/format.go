@@ -0,0 +1,190 @@
+package notifier
+
+import (
+	"all_exchange_symbol/models"
+	"fmt"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// exchangeGroup is the symbols of one exchange split by market type, in the
+// order alerts list them.
+type exchangeGroup struct {
+	Exchange string
+	Markets  []marketGroup
+}
+
+type marketGroup struct {
+	Market  models.MarketType
+	Symbols []models.Symbol
+}
+
+// groupSymbols groups symbols by exchange (sorted by name) and market type
+// (in models.AllMarketTypes order), keeping symbol order within a market.
+func groupSymbols(symbols []models.Symbol) []exchangeGroup {
+	byExchange := make(map[string]map[models.MarketType][]models.Symbol)
+	for _, symbol := range symbols {
+		if byExchange[symbol.Exchange] == nil {
+			byExchange[symbol.Exchange] = make(map[models.MarketType][]models.Symbol)
+		}
+		byExchange[symbol.Exchange][symbol.Type] = append(byExchange[symbol.Exchange][symbol.Type], symbol)
+	}
+
+	exchanges := make([]string, 0, len(byExchange))
+	for exchange := range byExchange {
+		exchanges = append(exchanges, exchange)
+	}
+	sort.Strings(exchanges)
+
+	groups := make([]exchangeGroup, 0, len(exchanges))
+	for _, exchange := range exchanges {
+		group := exchangeGroup{Exchange: exchange}
+		for _, market := range marketOrder(byExchange[exchange]) {
+			group.Markets = append(group.Markets, marketGroup{Market: market, Symbols: byExchange[exchange][market]})
+		}
+		groups = append(groups, group)
+	}
+	return groups
+}
+
+// marketOrder lists the markets present in byMarket, known types first.
+func marketOrder(byMarket map[models.MarketType][]models.Symbol) []models.MarketType {
+	var markets []models.MarketType
+	known := make(map[models.MarketType]bool)
+	for _, market := range models.AllMarketTypes {
+		known[market] = true
+		if len(byMarket[market]) > 0 {
+			markets = append(markets, market)
+		}
+	}
+
+	var other []models.MarketType
+	for market := range byMarket {
+		if !known[market] {
+			other = append(other, market)
+		}
+	}
+	sort.Slice(other, func(i, j int) bool { return other[i] < other[j] })
+
+	return append(markets, other...)
+}
+
+// markup is the text syntax of one chat platform. escape makes plain text
+// safe, bold wraps already escaped text, code renders a raw symbol name and
+// length measures text the way the platform applies its limit. lineBreak
+// joins lines and defaults to "\n".
+type markup struct {
+	escape    func(string) string
+	bold      func(string) string
+	code      func(string) string
+	length    func(string) int
+	lineBreak string
+}
+
+// part is one message of a split alert: a plain text title, carrying (i/n)
+// when there are several parts, and a body in the platform's markup.
+type part struct {
+	Title string
+	Body  string
+}
+
+// lines renders the plain lines of an alert followed by its symbols, grouped
+// by exchange and market type, listing every symbol.
+func (m markup) lines(alert Alert) []string {
+	var lines []string
+	for _, line := range alert.Lines {
+		lines = append(lines, m.escape(line))
+	}
+
+	for _, group := range groupSymbols(alert.Symbols) {
+		lines = append(lines, fmt.Sprintf("📊 %s:", m.bold(m.escape(group.Exchange))))
+		for _, market := range group.Markets {
+			lines = append(lines, fmt.Sprintf("   • %s: %d symbols", m.escape(market.Market.Label()), len(market.Symbols)))
+			for _, symbol := range market.Symbols {
+				lines = append(lines, "      - "+m.code(symbol.Symbol))
+			}
+		}
+		lines = append(lines, "")
+	}
+	return lines
+}
+
+// parts splits the rendered alert into bodies of at most budget.
+func (m markup) parts(alert Alert, budget int) []part {
+	lineBreak := m.lineBreak
+	if lineBreak == "" {
+		lineBreak = "\n"
+	}
+
+	bodies := splitLines(m.lines(alert), budget, lineBreak, m.length)
+	parts := make([]part, len(bodies))
+	for i, body := range bodies {
+		parts[i] = part{Title: alert.Title, Body: body}
+		if len(bodies) > 1 {
+			parts[i].Title += fmt.Sprintf(" (%d/%d)", i+1, len(bodies))
+		}
+	}
+	return parts
+}
+
+// messages renders an alert into as few messages of at most limit as
+// possible, each starting with the bold title.
+func (m markup) messages(alert Alert, limit int) []string {
+	// room for the title markup, a " (99/99)" marker and the blank line after it
+	budget := limit - m.length(m.bold(m.escape(alert.Title))) - 32
+
+	parts := m.parts(alert, budget)
+	messages := make([]string, len(parts))
+	for i, part := range parts {
+		messages[i] = m.bold(m.escape(part.Title)) + "\n\n" + part.Body
+	}
+	return messages
+}
+
+// splitLines packs lines, joined by lineBreak, into bodies of at most
+// budget, breaking only between lines so no markup is cut. There is always
+// at least one body.
+func splitLines(lines []string, budget int, lineBreak string, length func(string) int) []string {
+	var bodies []string
+	var body strings.Builder
+	for _, line := range lines {
+		if body.Len() > 0 && length(body.String())+length(lineBreak)+length(line) > budget {
+			bodies = append(bodies, strings.TrimRight(body.String(), lineBreak))
+			body.Reset()
+		}
+		if body.Len() > 0 {
+			body.WriteString(lineBreak)
+		}
+		body.WriteString(line)
+	}
+	return append(bodies, strings.TrimRight(body.String(), lineBreak))
+}
+
+// utf16Length counts UTF-16 code units, which is how Telegram measures its
+// limit; emoji outside the BMP count twice.
+func utf16Length(text string) int {
+	n := 0
+	for _, r := range text {
+		if r >= 0x10000 {
+			n += 2
+		} else {
+			n++
+		}
+	}
+	return n
+}
+
+// byteLength is for platforms that limit the UTF-8 size of a message.
+func byteLength(text string) int {
+	return len(text)
+}
+
+// Truncate shortens text to at most limit runes, marking the cut.
+func Truncate(text string, limit int) string {
+	if utf8.RuneCountInString(text) <= limit {
+		return text
+	}
+	runes := []rune(text)
+	return string(runes[:limit]) + "…"
+}
@@ -0,0 +1,73 @@
+package notifier
+
+import (
+	"all_exchange_symbol/models"
+	"fmt"
+	"regexp"
+	"strings"
+	"testing"
+	"unicode/utf16"
+)
+
+func TestUTF16Length(t *testing.T) {
+	for _, text := range []string{"", "BTCUSDT", "é", "📊 binance", "🚀🟢⏸ ok"} {
+		if got, want := utf16Length(text), len(utf16.Encode([]rune(text))); got != want {
+			t.Errorf("utf16Length(%q) = %d, want %d", text, got, want)
+		}
+	}
+}
+
+func TestTelegramSplitsLongAlerts(t *testing.T) {
+	alert := Alert{Kind: AlertNewSymbols, Title: "🆕 New symbols"}
+	// non-BMP emoji count as two UTF-16 units but one rune
+	for i := 0; i < 200; i++ {
+		alert.Lines = append(alert.Lines, fmt.Sprintf("🚀🚀🚀 line %03d <&>", i))
+	}
+	var want []string
+	for i := 0; i < 400; i++ {
+		name := fmt.Sprintf("S%03d<X>USDT", i)
+		want = append(want, name)
+		alert.Symbols = append(alert.Symbols, models.Symbol{Exchange: "binance", Type: models.MarketSpot, Symbol: name})
+	}
+
+	messages := NewTelegram("", "").Format(alert)
+	if len(messages) < 3 {
+		t.Fatalf("Format returned %d messages, want a split into at least 3", len(messages))
+	}
+
+	code := regexp.MustCompile(`<code>(.*?)</code>`)
+	var got []string
+	for i, message := range messages {
+		if n := len(utf16.Encode([]rune(message))); n > telegramMaxLength {
+			t.Errorf("message %d is %d UTF-16 units, over %d", i+1, n, telegramMaxLength)
+		}
+
+		title := fmt.Sprintf("<b>🆕 New symbols (%d/%d)</b>\n\n", i+1, len(messages))
+		if !strings.HasPrefix(message, title) {
+			t.Errorf("message %d starts with %q, want %q", i+1, firstLine(message), strings.TrimSpace(title))
+		}
+
+		// every tag opened in a message is closed in it
+		for _, tag := range []string{"b", "code"} {
+			if open, closed := strings.Count(message, "<"+tag+">"), strings.Count(message, "</"+tag+">"); open != closed {
+				t.Errorf("message %d has %d <%s> and %d </%s>", i+1, open, tag, closed, tag)
+			}
+		}
+		if strings.Contains(message, "<X>") || strings.Contains(message, "<&>") {
+			t.Errorf("message %d contains unescaped text", i+1)
+		}
+
+		for _, m := range code.FindAllStringSubmatch(message, -1) {
+			got = append(got, strings.ReplaceAll(strings.ReplaceAll(m[1], "&lt;", "<"), "&gt;", ">"))
+		}
+	}
+
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("symbols across the parts are not complete and in order: got %d, want %d", len(got), len(want))
+	}
+}
+
+func firstLine(text string) string {
+	line, _, _ := strings.Cut(text, "\n")
+	return line
+}
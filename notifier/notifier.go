@@ -0,0 +1,169 @@
+package notifier
+
+import (
+	"all_exchange_symbol/models"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	neturl "net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// AlertKind identifies what an alert announces; status events use their
+// models.StatusEvent value.
+type AlertKind string
+
+const (
+	AlertNewSymbols AlertKind = "new_symbols"
+	AlertDelisted   AlertKind = "delisted"
+	AlertRelisted   AlertKind = "relisted"
+	AlertGuard      AlertKind = "guard_warning"
+	AlertSummary    AlertKind = "summary"
+)
+
+// Alert is one notification independent of the platform it goes to. Title
+// and Lines are plain text; every Notifier escapes them for its own markup.
+type Alert struct {
+	Kind    AlertKind       `json:"kind"`
+	Title   string          `json:"title"`
+	Lines   []string        `json:"lines,omitempty"`
+	Symbols []models.Symbol `json:"symbols,omitempty"`
+}
+
+// Notifier delivers alerts to one platform. Format renders an alert into the
+// messages to send, in order, each within the platform's size limit; the
+// writer stores them in the outbox and hands them to Send one at a time, so
+// Send must not keep state between calls.
+type Notifier interface {
+	// Name is the outbox channel of the notifier, e.g. "telegram".
+	Name() string
+	Format(alert Alert) []string
+	Send(ctx context.Context, message string) error
+}
+
+// Scheduler is implemented by notifiers that hold messages back, such as a
+// daily digest. NextSend returns when a message queued at queuedAt is due.
+type Scheduler interface {
+	NextSend(queuedAt time.Time) time.Time
+}
+
+// Batcher is implemented by notifiers that merge all due messages into as
+// few sends as possible. SendBatch returns one error per message, nil for
+// the ones that were delivered.
+type Batcher interface {
+	SendBatch(ctx context.Context, messages []string) []error
+}
+
+// Error is a request the platform answered with a non-success status.
+type Error struct {
+	Notifier    string
+	StatusCode  int
+	Description string
+	RetryAfter  time.Duration // requested wait before the next attempt, if any
+}
+
+func (e *Error) Error() string {
+	if e.Description == "" {
+		return fmt.Sprintf("%s API error: status code %d", e.Notifier, e.StatusCode)
+	}
+	return fmt.Sprintf("%s API error: status code %d: %s", e.Notifier, e.StatusCode, e.Description)
+}
+
+// Temporary reports whether the same message may succeed later. Other 4xx
+// answers, such as a malformed message or a revoked webhook, fail every time.
+func (e *Error) Temporary() bool {
+	return e.StatusCode == http.StatusTooManyRequests || e.StatusCode >= http.StatusInternalServerError
+}
+
+// robotRateLimitWait is how long chat robots limited to about 20 messages
+// per minute (DingTalk, WeCom, Feishu) are left alone after hitting it.
+const robotRateLimitWait = time.Minute
+
+// robotError turns the business code of a chat robot API, which answers
+// HTTP 200 even on failure, into *Error. A used up message quota is reported
+// as 429 so it is retried; any other code, such as a bad signature or a
+// missing keyword, fails the same way on every attempt.
+func robotError(name string, code int, msg string, rateLimited bool) error {
+	if code == 0 {
+		return nil
+	}
+
+	err := &Error{
+		Notifier:    name,
+		StatusCode:  http.StatusBadRequest,
+		Description: fmt.Sprintf("code %d: %s", code, msg),
+	}
+	if rateLimited {
+		err.StatusCode = http.StatusTooManyRequests
+		err.RetryAfter = robotRateLimitWait
+	}
+	return err
+}
+
+// badRobotResponse reports a robot answer that is not the expected JSON,
+// such as a proxy error page, as a bad gateway so it is retried.
+func badRobotResponse(name string, data []byte) error {
+	return &Error{Notifier: name, StatusCode: http.StatusBadGateway, Description: Truncate(string(data), maxDescriptionLength)}
+}
+
+// maxDescriptionLength keeps error bodies short enough for logs and the outbox.
+const maxDescriptionLength = 300
+
+func newHTTPClient() *http.Client {
+	return &http.Client{Timeout: 10 * time.Second}
+}
+
+// post sends a JSON body and returns the response body. A non-2xx answer is
+// returned as *Error, with RetryAfter taken from the Retry-After header.
+func post(ctx context.Context, client *http.Client, name, url string, body []byte, header http.Header) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for key, values := range header {
+		req.Header[key] = values
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		var urlErr *neturl.Error
+		if errors.As(err, &urlErr) {
+			// the URL carries the bot token or webhook secret; keep it out of logs and the outbox
+			return nil, fmt.Errorf("%s request failed: %w", name, urlErr.Err)
+		}
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return data, &Error{
+			Notifier:    name,
+			StatusCode:  resp.StatusCode,
+			Description: Truncate(strings.TrimSpace(string(data)), maxDescriptionLength),
+			RetryAfter:  parseRetryAfter(resp.Header.Get("Retry-After")),
+		}
+	}
+
+	return data, nil
+}
+
+// parseRetryAfter reads a Retry-After header given in (possibly fractional)
+// seconds. HTTP dates are not used by any supported platform.
+func parseRetryAfter(value string) time.Duration {
+	seconds, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+	if err != nil || seconds <= 0 {
+		return 0
+	}
+	return time.Duration(seconds * float64(time.Second))
+}
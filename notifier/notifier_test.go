@@ -0,0 +1,367 @@
+package notifier
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+// request is what a test server received.
+type request struct {
+	Path   string
+	Query  url.Values
+	Header http.Header
+	Body   []byte
+}
+
+// newServer answers every request with status, header and body and records
+// the requests it got.
+func newServer(t *testing.T, status int, header http.Header, body string) (*httptest.Server, *[]request) {
+	t.Helper()
+
+	var requests []request
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		requests = append(requests, request{Path: r.URL.Path, Query: r.URL.Query(), Header: r.Header.Clone(), Body: data})
+		for key, values := range header {
+			w.Header()[key] = values
+		}
+		w.WriteHeader(status)
+		io.WriteString(w, body)
+	}))
+	t.Cleanup(server.Close)
+	return server, &requests
+}
+
+func decode(t *testing.T, data []byte) map[string]interface{} {
+	t.Helper()
+
+	var payload map[string]interface{}
+	if err := json.Unmarshal(data, &payload); err != nil {
+		t.Fatalf("payload is not JSON: %v: %s", err, data)
+	}
+	return payload
+}
+
+func apiError(t *testing.T, err error) *Error {
+	t.Helper()
+
+	var apiErr *Error
+	if !errors.As(err, &apiErr) {
+		t.Fatalf("error %v (%T) is not *Error", err, err)
+	}
+	return apiErr
+}
+
+func TestTelegramPayload(t *testing.T) {
+	server, requests := newServer(t, http.StatusOK, nil, `{"ok":true}`)
+	telegram := NewTelegram("123:abc", "-100")
+	telegram.BaseURL = server.URL
+
+	if err := telegram.Send(context.Background(), "<b>hi</b>"); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+
+	got := (*requests)[0]
+	if got.Path != "/bot123:abc/sendMessage" {
+		t.Errorf("path %q, want /bot123:abc/sendMessage", got.Path)
+	}
+	payload := decode(t, got.Body)
+	if payload["chat_id"] != "-100" || payload["text"] != "<b>hi</b>" || payload["parse_mode"] != "HTML" {
+		t.Errorf("payload %v", payload)
+	}
+}
+
+func TestTelegramFormatEscapesHTML(t *testing.T) {
+	messages := NewTelegram("", "").Format(Alert{Kind: AlertGuard, Title: "a < b", Lines: []string{"x & y"}})
+	if len(messages) != 1 || !strings.Contains(messages[0], "a &lt; b") || !strings.Contains(messages[0], "x &amp; y") {
+		t.Errorf("messages %q", messages)
+	}
+}
+
+func TestTelegramRetryAfter(t *testing.T) {
+	server, _ := newServer(t, http.StatusTooManyRequests, nil,
+		`{"ok":false,"error_code":429,"description":"Too Many Requests: retry after 7","parameters":{"retry_after":7}}`)
+	telegram := NewTelegram("123:abc", "-100")
+	telegram.BaseURL = server.URL
+
+	apiErr := apiError(t, telegram.Send(context.Background(), "hi"))
+	if apiErr.RetryAfter != 7*time.Second {
+		t.Errorf("RetryAfter %v, want 7s", apiErr.RetryAfter)
+	}
+	if apiErr.Description != "Too Many Requests: retry after 7" {
+		t.Errorf("Description %q", apiErr.Description)
+	}
+	if !apiErr.Temporary() {
+		t.Error("429 is not temporary")
+	}
+}
+
+func TestDiscordPayload(t *testing.T) {
+	server, requests := newServer(t, http.StatusNoContent, nil, "")
+
+	if err := NewDiscord(server.URL).Send(context.Background(), "**hi** @everyone"); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+
+	payload := decode(t, (*requests)[0].Body)
+	if payload["content"] != "**hi** @everyone" {
+		t.Errorf("content %v", payload["content"])
+	}
+	mentions, ok := payload["allowed_mentions"].(map[string]interface{})
+	if !ok {
+		t.Fatalf("allowed_mentions missing: %v", payload)
+	}
+	if parse, ok := mentions["parse"].([]interface{}); !ok || len(parse) != 0 {
+		t.Errorf("allowed_mentions.parse %v, want []", mentions["parse"])
+	}
+}
+
+func TestDiscordRetryAfterBody(t *testing.T) {
+	server, _ := newServer(t, http.StatusTooManyRequests, nil,
+		`{"message":"You are being rate limited.","retry_after":1.5,"global":false}`)
+
+	apiErr := apiError(t, NewDiscord(server.URL).Send(context.Background(), "hi"))
+	if apiErr.RetryAfter != 1500*time.Millisecond {
+		t.Errorf("RetryAfter %v, want 1.5s", apiErr.RetryAfter)
+	}
+}
+
+func TestSlackPayload(t *testing.T) {
+	server, requests := newServer(t, http.StatusOK, nil, "ok")
+
+	if err := NewSlack(server.URL).Send(context.Background(), "*hi*"); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+
+	got := (*requests)[0]
+	if got.Header.Get("Content-Type") != "application/json" {
+		t.Errorf("Content-Type %q", got.Header.Get("Content-Type"))
+	}
+	if payload := decode(t, got.Body); payload["text"] != "*hi*" {
+		t.Errorf("payload %v", payload)
+	}
+}
+
+func TestRetryAfterHeader(t *testing.T) {
+	server, _ := newServer(t, http.StatusTooManyRequests, http.Header{"Retry-After": {"3"}}, "rate_limited")
+
+	apiErr := apiError(t, NewSlack(server.URL).Send(context.Background(), "hi"))
+	if apiErr.RetryAfter != 3*time.Second {
+		t.Errorf("RetryAfter %v, want 3s", apiErr.RetryAfter)
+	}
+	if apiErr.Description != "rate_limited" {
+		t.Errorf("Description %q", apiErr.Description)
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	tests := map[string]time.Duration{
+		"":      0,
+		"5":     5 * time.Second,
+		" 2.5 ": 2500 * time.Millisecond,
+		"0":     0,
+		"-1":    0,
+		"soon":  0,
+	}
+	for value, want := range tests {
+		if got := parseRetryAfter(value); got != want {
+			t.Errorf("parseRetryAfter(%q) = %v, want %v", value, got, want)
+		}
+	}
+}
+
+func TestWebhookSignature(t *testing.T) {
+	server, requests := newServer(t, http.StatusOK, nil, "")
+	webhook := NewWebhook(server.URL, "s3cret")
+
+	messages := webhook.Format(Alert{Kind: AlertNewSymbols, Title: "1 new"})
+	if len(messages) != 1 {
+		t.Fatalf("Format returned %d messages, want 1", len(messages))
+	}
+	if err := webhook.Send(context.Background(), messages[0]); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+
+	got := (*requests)[0]
+	if payload := decode(t, got.Body); payload["kind"] != string(AlertNewSymbols) || payload["title"] != "1 new" {
+		t.Errorf("payload %v", payload)
+	}
+
+	timestamp := got.Header.Get("X-Signature-Timestamp")
+	if timestamp == "" {
+		t.Fatal("X-Signature-Timestamp missing")
+	}
+	// recompute the signature the way a receiver would
+	mac := hmac.New(sha256.New, []byte("s3cret"))
+	mac.Write([]byte(timestamp + "." + string(got.Body)))
+	want := "sha256=" + hex.EncodeToString(mac.Sum(nil))
+	if signature := got.Header.Get("X-Signature-256"); !hmac.Equal([]byte(signature), []byte(want)) {
+		t.Errorf("X-Signature-256 %q, want %q", signature, want)
+	}
+}
+
+func TestWebhookWithoutSecretIsUnsigned(t *testing.T) {
+	server, requests := newServer(t, http.StatusOK, nil, "")
+
+	if err := NewWebhook(server.URL, "").Send(context.Background(), `{}`); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+	if got := (*requests)[0].Header.Get("X-Signature-256"); got != "" {
+		t.Errorf("unsigned webhook sent X-Signature-256 %q", got)
+	}
+}
+
+func TestErrorClassification(t *testing.T) {
+	tests := []struct {
+		status    int
+		temporary bool
+	}{
+		{http.StatusBadRequest, false},
+		{http.StatusUnauthorized, false},
+		{http.StatusForbidden, false},
+		{http.StatusNotFound, false},
+		{http.StatusTooManyRequests, true},
+		{http.StatusInternalServerError, true},
+		{http.StatusBadGateway, true},
+		{http.StatusServiceUnavailable, true},
+	}
+
+	for _, tt := range tests {
+		server, _ := newServer(t, tt.status, nil, "")
+		apiErr := apiError(t, NewSlack(server.URL).Send(context.Background(), "hi"))
+		if apiErr.StatusCode != tt.status {
+			t.Errorf("StatusCode %d, want %d", apiErr.StatusCode, tt.status)
+		}
+		if apiErr.Temporary() != tt.temporary {
+			t.Errorf("status %d: Temporary() = %v, want %v", tt.status, apiErr.Temporary(), tt.temporary)
+		}
+	}
+}
+
+func TestPostHidesURLOnNetworkError(t *testing.T) {
+	server, _ := newServer(t, http.StatusOK, nil, "")
+	url := server.URL + "/hooks/secret-token"
+	server.Close()
+
+	err := NewSlack(url).Send(context.Background(), "hi")
+	if err == nil {
+		t.Fatal("Send to a closed server succeeded")
+	}
+	if strings.Contains(err.Error(), "secret-token") {
+		t.Errorf("error leaks the webhook URL: %v", err)
+	}
+}
+
+func TestFeishuSignature(t *testing.T) {
+	server, requests := newServer(t, http.StatusOK, nil, `{"code":0,"msg":"success"}`)
+	feishu := NewFeishu(server.URL, "s3cret")
+
+	messages := feishu.Format(Alert{Kind: AlertNewSymbols, Title: "1 new", Lines: []string{"BTCUSDT"}})
+	if len(messages) != 1 {
+		t.Fatalf("Format returned %d messages, want 1", len(messages))
+	}
+	if err := feishu.Send(context.Background(), messages[0]); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+
+	payload := decode(t, (*requests)[0].Body)
+	if payload["msg_type"] != "interactive" {
+		t.Errorf("msg_type %v, want interactive", payload["msg_type"])
+	}
+	if _, ok := payload["card"].(map[string]interface{}); !ok {
+		t.Errorf("card %v is not an object", payload["card"])
+	}
+
+	timestamp, _ := payload["timestamp"].(string)
+	if timestamp == "" {
+		t.Fatal("timestamp missing")
+	}
+	// Feishu keys the HMAC with timestamp + "\n" + secret over an empty message
+	mac := hmac.New(sha256.New, []byte(timestamp+"\n"+"s3cret"))
+	want := base64.StdEncoding.EncodeToString(mac.Sum(nil))
+	if sign, _ := payload["sign"].(string); !hmac.Equal([]byte(sign), []byte(want)) {
+		t.Errorf("sign %q, want %q", sign, want)
+	}
+}
+
+func TestDingTalkSignature(t *testing.T) {
+	server, requests := newServer(t, http.StatusOK, nil, `{"errcode":0,"errmsg":"ok"}`)
+	dingTalk := NewDingTalk(server.URL+"/robot/send?access_token=abc", "s3cret", "")
+
+	if err := dingTalk.Send(context.Background(), `{"msgtype":"markdown"}`); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+
+	query := (*requests)[0].Query
+	if query.Get("access_token") != "abc" {
+		t.Errorf("access_token %q, want abc", query.Get("access_token"))
+	}
+	timestamp := query.Get("timestamp")
+	if timestamp == "" {
+		t.Fatal("timestamp missing")
+	}
+	mac := hmac.New(sha256.New, []byte("s3cret"))
+	mac.Write([]byte(timestamp + "\n" + "s3cret"))
+	want := base64.StdEncoding.EncodeToString(mac.Sum(nil))
+	if sign := query.Get("sign"); !hmac.Equal([]byte(sign), []byte(want)) {
+		t.Errorf("sign %q, want %q", sign, want)
+	}
+}
+
+func TestRobotBusinessCodes(t *testing.T) {
+	feishu := func(url string) Notifier { return NewFeishu(url, "") }
+	dingTalk := func(url string) Notifier { return NewDingTalk(url, "", "") }
+	weCom := func(url string) Notifier { return NewWeCom(url) }
+
+	tests := []struct {
+		name      string
+		notifier  func(url string) Notifier
+		body      string
+		ok        bool
+		temporary bool
+	}{
+		{"feishu ok", feishu, `{"code":0,"msg":"success"}`, true, false},
+		{"feishu legacy ok", feishu, `{"StatusCode":0,"StatusMessage":"success"}`, true, false},
+		{"feishu rate limited", feishu, `{"code":11232,"msg":"frequency limited psm"}`, false, true},
+		{"feishu keyword rejected", feishu, `{"code":19024,"msg":"Key Words Not Found"}`, false, false},
+		{"feishu legacy error", feishu, `{"StatusCode":19021,"StatusMessage":"sign match fail"}`, false, false},
+		{"feishu not JSON", feishu, `<html>bad gateway</html>`, false, true},
+		{"dingtalk ok", dingTalk, `{"errcode":0,"errmsg":"ok"}`, true, false},
+		{"dingtalk rate limited", dingTalk, `{"errcode":130101,"errmsg":"send too fast"}`, false, true},
+		{"dingtalk keyword rejected", dingTalk, `{"errcode":310000,"errmsg":"keywords not in content"}`, false, false},
+		{"dingtalk not JSON", dingTalk, ``, false, true},
+		{"wecom rate limited", weCom, `{"errcode":45009,"errmsg":"api freq out of limit"}`, false, true},
+		{"wecom not JSON", weCom, `oops`, false, true},
+	}
+
+	for _, tt := range tests {
+		server, _ := newServer(t, http.StatusOK, nil, tt.body)
+		err := tt.notifier(server.URL).Send(context.Background(), `{}`)
+		if tt.ok {
+			if err != nil {
+				t.Errorf("%s: %v", tt.name, err)
+			}
+			continue
+		}
+
+		apiErr := apiError(t, err)
+		if apiErr.Temporary() != tt.temporary {
+			t.Errorf("%s: Temporary() = %v, want %v (%v)", tt.name, apiErr.Temporary(), tt.temporary, apiErr)
+		}
+		if tt.temporary && apiErr.StatusCode == http.StatusTooManyRequests && apiErr.RetryAfter != robotRateLimitWait {
+			t.Errorf("%s: RetryAfter %v, want %v", tt.name, apiErr.RetryAfter, robotRateLimitWait)
+		}
+	}
+}
@@ -0,0 +1,57 @@
+package notifier
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"unicode/utf8"
+)
+
+// slackMaxLength keeps messages under the 4,000 characters Slack renders
+// without truncating.
+const slackMaxLength = 4000
+
+var slackEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+
+var slackMarkup = markup{
+	escape: slackEscaper.Replace,
+	bold:   func(text string) string { return "*" + text + "*" },
+	code:   func(text string) string { return "`" + slackEscaper.Replace(strings.ReplaceAll(text, "`", "'")) + "`" },
+	length: utf8.RuneCountInString,
+}
+
+// Slack posts mrkdwn messages to an incoming webhook.
+type Slack struct {
+	webhookURL string
+	client     *http.Client
+}
+
+type slackMessage struct {
+	Text string `json:"text"`
+}
+
+func NewSlack(webhookURL string) *Slack {
+	return &Slack{
+		webhookURL: webhookURL,
+		client:     newHTTPClient(),
+	}
+}
+
+func (s *Slack) Name() string {
+	return "slack"
+}
+
+func (s *Slack) Format(alert Alert) []string {
+	return slackMarkup.messages(alert, slackMaxLength)
+}
+
+func (s *Slack) Send(ctx context.Context, message string) error {
+	body, err := json.Marshal(slackMessage{Text: message})
+	if err != nil {
+		return err
+	}
+
+	_, err = post(ctx, s.client, s.Name(), s.webhookURL, body, nil)
+	return err
+}
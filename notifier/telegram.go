@@ -0,0 +1,89 @@
+package notifier
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"net/http"
+	"time"
+)
+
+// telegramMaxLength is the sendMessage text limit in UTF-16 code units.
+const telegramMaxLength = 4096
+
+var telegramMarkup = markup{
+	escape: html.EscapeString,
+	bold:   func(text string) string { return "<b>" + text + "</b>" },
+	code:   func(text string) string { return "<code>" + html.EscapeString(text) + "</code>" },
+	length: utf16Length,
+}
+
+// Telegram sends HTML messages through a bot's sendMessage method.
+type Telegram struct {
+	BaseURL  string // https://api.telegram.org, replaceable for tests
+	botToken string
+	chatID   string
+	client   *http.Client
+}
+
+type TelegramMessage struct {
+	ChatID    string `json:"chat_id"`
+	Text      string `json:"text"`
+	ParseMode string `json:"parse_mode"`
+}
+
+type telegramResponse struct {
+	Description string `json:"description"`
+	Parameters  struct {
+		RetryAfter int `json:"retry_after"`
+	} `json:"parameters"`
+}
+
+func NewTelegram(botToken, chatID string) *Telegram {
+	return &Telegram{
+		BaseURL:  "https://api.telegram.org",
+		botToken: botToken,
+		chatID:   chatID,
+		client:   newHTTPClient(),
+	}
+}
+
+func (t *Telegram) Name() string {
+	return "telegram"
+}
+
+func (t *Telegram) Format(alert Alert) []string {
+	return telegramMarkup.messages(alert, telegramMaxLength)
+}
+
+// Send posts one HTML message. On 429 Telegram reports the wait in
+// parameters.retry_after rather than a Retry-After header.
+func (t *Telegram) Send(ctx context.Context, message string) error {
+	body, err := json.Marshal(TelegramMessage{
+		ChatID:    t.chatID,
+		Text:      message,
+		ParseMode: "HTML",
+	})
+	if err != nil {
+		return err
+	}
+
+	url := fmt.Sprintf("%s/bot%s/sendMessage", t.BaseURL, t.botToken)
+	data, err := post(ctx, t.client, t.Name(), url, body, nil)
+
+	var apiErr *Error
+	if errors.As(err, &apiErr) {
+		var response telegramResponse
+		if json.Unmarshal(data, &response) == nil {
+			if response.Description != "" {
+				apiErr.Description = response.Description
+			}
+			if response.Parameters.RetryAfter > 0 {
+				apiErr.RetryAfter = time.Duration(response.Parameters.RetryAfter) * time.Second
+			}
+		}
+	}
+	return err
+}
@@ -0,0 +1,69 @@
+package notifier
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Webhook posts every alert as JSON to a generic endpoint. With a secret,
+// each request is signed so the receiver can verify its origin:
+//
+//	X-Signature-Timestamp: unix seconds of the attempt
+//	X-Signature-256:       sha256=hex(HMAC-SHA256(secret, timestamp + "." + body))
+type Webhook struct {
+	url    string
+	secret string
+	client *http.Client
+}
+
+func NewWebhook(url, secret string) *Webhook {
+	return &Webhook{
+		url:    url,
+		secret: secret,
+		client: newHTTPClient(),
+	}
+}
+
+func (w *Webhook) Name() string {
+	return "webhook"
+}
+
+// Format encodes the whole alert as one JSON document; receivers get the
+// full symbol records, so nothing is split.
+func (w *Webhook) Format(alert Alert) []string {
+	body, err := json.Marshal(alert)
+	if err != nil {
+		log.Printf("Error encoding webhook alert %s: %v", alert.Kind, err)
+		return nil
+	}
+	return []string{string(body)}
+}
+
+func (w *Webhook) Send(ctx context.Context, message string) error {
+	header := http.Header{}
+	if w.secret != "" {
+		timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+		header.Set("X-Signature-Timestamp", timestamp)
+		header.Set("X-Signature-256", "sha256="+Sign(w.secret, timestamp, []byte(message)))
+	}
+
+	_, err := post(ctx, w.client, w.Name(), w.url, []byte(message), header)
+	return err
+}
+
+// Sign returns the hex HMAC-SHA256 of timestamp + "." + body, the value a
+// receiver recomputes to verify X-Signature-256.
+func Sign(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,76 @@
+package notifier
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+)
+
+// weComMaxLength is the markdown content limit of a group robot in UTF-8
+// bytes.
+const weComMaxLength = 4096
+
+// weComRateLimited is the business code of a robot that sent more than 20
+// messages in a minute.
+const weComRateLimited = 45009
+
+var weComMarkup = markup{
+	escape: func(text string) string { return strings.ReplaceAll(text, "`", "'") },
+	bold:   func(text string) string { return "**" + text + "**" },
+	code:   func(text string) string { return "`" + strings.ReplaceAll(text, "`", "'") + "`" },
+	length: byteLength,
+}
+
+// WeCom posts markdown messages to a WeCom (企业微信) group robot.
+type WeCom struct {
+	webhookURL string
+	client     *http.Client
+}
+
+type weComMessage struct {
+	MsgType  string `json:"msgtype"`
+	Markdown struct {
+		Content string `json:"content"`
+	} `json:"markdown"`
+}
+
+func NewWeCom(webhookURL string) *WeCom {
+	return &WeCom{
+		webhookURL: webhookURL,
+		client:     newHTTPClient(),
+	}
+}
+
+func (w *WeCom) Name() string {
+	return "wecom"
+}
+
+func (w *WeCom) Format(alert Alert) []string {
+	return weComMarkup.messages(alert, weComMaxLength)
+}
+
+func (w *WeCom) Send(ctx context.Context, message string) error {
+	var payload weComMessage
+	payload.MsgType = "markdown"
+	payload.Markdown.Content = message
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	data, err := post(ctx, w.client, w.Name(), w.webhookURL, body, nil)
+	if err != nil {
+		return err
+	}
+
+	var response struct {
+		ErrCode int    `json:"errcode"`
+		ErrMsg  string `json:"errmsg"`
+	}
+	if err := json.Unmarshal(data, &response); err != nil {
+		return badRobotResponse(w.Name(), data)
+	}
+	return robotError(w.Name(), response.ErrCode, response.ErrMsg, response.ErrCode == weComRateLimited)
+}
@@ -0,0 +1,69 @@
+package processor
+
+import (
+	"all_exchange_symbol/models"
+	"log"
+	"time"
+)
+
+// DelistGuard protects against truncated or failed responses being read as
+// an exchange removing most of its markets. A drop is still applied when the
+// market is too small for a ratio to mean anything, or once it has persisted
+// for ConfirmAfter. An empty list is held whatever the market size.
+type DelistGuard struct {
+	DefaultThreshold   float64
+	ExchangeThresholds map[string]float64
+	MinStoredCount     int           // smaller markets are only held when the list is empty
+	ConfirmAfter       time.Duration // how long a drop is held before it is applied, 0 holds forever
+}
+
+func DefaultDelistGuard() DelistGuard {
+	return DelistGuard{DefaultThreshold: 0.2, MinStoredCount: 20, ConfirmAfter: time.Hour}
+}
+
+func (g DelistGuard) ThresholdFor(exchange string) float64 {
+	if threshold, ok := g.ExchangeThresholds[exchange]; ok {
+		return threshold
+	}
+	return g.DefaultThreshold
+}
+
+// Check returns a warning when fetched dropped below stored by more than the
+// exchange's threshold, or nil when the drop can be treated as real. held is
+// the market's state after the previous poll.
+func (g DelistGuard) Check(exchange string, market models.MarketType, storedCount, fetchedCount int, held models.GuardState, now time.Time) *models.GuardWarning {
+	if storedCount == 0 || fetchedCount >= storedCount {
+		return nil
+	}
+
+	threshold := g.ThresholdFor(exchange)
+	dropRatio := float64(storedCount-fetchedCount) / float64(storedCount)
+	// an HTTP 200 with an empty list is never trusted right away
+	if fetchedCount > 0 && (storedCount < g.MinStoredCount || dropRatio <= threshold) {
+		return nil
+	}
+
+	heldSince := now
+	if held.HeldPolls > 0 && held.HeldSince != nil {
+		heldSince = *held.HeldSince
+	}
+	if g.ConfirmAfter > 0 && now.Sub(heldSince) >= g.ConfirmAfter {
+		log.Printf("%s %s 交易对数量自 %s 起保持在 %d (原 %d)，按真实下架处理",
+			exchange, market, heldSince.Format("2006-01-02 15:04:05"), fetchedCount, storedCount)
+		return nil
+	}
+
+	log.Printf("警告: %s %s 交易对数量从 %d 降至 %d (下降 %.1f%%，阈值 %.1f%%)，本次不处理下架 (连续第 %d 次)",
+		exchange, market, storedCount, fetchedCount, dropRatio*100, threshold*100, held.HeldPolls+1)
+
+	return &models.GuardWarning{
+		Exchange:     exchange,
+		Market:       market,
+		StoredCount:  storedCount,
+		FetchedCount: fetchedCount,
+		DropRatio:    dropRatio,
+		Threshold:    threshold,
+		HeldPolls:    held.HeldPolls + 1,
+		HeldSince:    heldSince,
+	}
+}
@@ -0,0 +1,57 @@
+package processor
+
+import (
+	"all_exchange_symbol/models"
+	"testing"
+	"time"
+)
+
+func TestDelistGuardCheck(t *testing.T) {
+	guard := DelistGuard{DefaultThreshold: 0.2, MinStoredCount: 20, ConfirmAfter: time.Hour}
+	now := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	since := now.Add(-30 * time.Minute)
+	expired := now.Add(-2 * time.Hour)
+
+	tests := []struct {
+		name          string
+		guard         DelistGuard
+		stored        int
+		fetched       int
+		held          models.GuardState
+		wantHeld      bool
+		wantPolls     int
+		wantHeldSince time.Time
+	}{
+		{name: "no stored symbols", guard: guard, stored: 0, fetched: 0},
+		{name: "small drop", guard: guard, stored: 100, fetched: 90},
+		{name: "sharp drop", guard: guard, stored: 100, fetched: 50, wantHeld: true, wantPolls: 1, wantHeldSince: now},
+		{name: "small market drop", guard: guard, stored: 5, fetched: 1},
+		{name: "small market empty", guard: guard, stored: 5, fetched: 0, wantHeld: true, wantPolls: 1, wantHeldSince: now},
+		{name: "still held", guard: guard, stored: 100, fetched: 50,
+			held: models.GuardState{HeldPolls: 3, HeldSince: &since}, wantHeld: true, wantPolls: 4, wantHeldSince: since},
+		{name: "confirmed", guard: guard, stored: 100, fetched: 50,
+			held: models.GuardState{HeldPolls: 700, HeldSince: &expired}},
+		{name: "empty list confirmed", guard: guard, stored: 100, fetched: 0,
+			held: models.GuardState{HeldPolls: 700, HeldSince: &expired}},
+		{name: "never confirmed", guard: DelistGuard{DefaultThreshold: 0.2}, stored: 100, fetched: 50,
+			held: models.GuardState{HeldPolls: 700, HeldSince: &expired}, wantHeld: true, wantPolls: 701, wantHeldSince: expired},
+	}
+
+	for _, tt := range tests {
+		warning := tt.guard.Check("binance", models.MarketSpot, tt.stored, tt.fetched, tt.held, now)
+		if (warning != nil) != tt.wantHeld {
+			t.Errorf("%s: held %v, want %v", tt.name, warning != nil, tt.wantHeld)
+			continue
+		}
+		if warning == nil {
+			continue
+		}
+		if warning.HeldPolls != tt.wantPolls || !warning.HeldSince.Equal(tt.wantHeldSince) {
+			t.Errorf("%s: held %d polls since %v, want %d since %v",
+				tt.name, warning.HeldPolls, warning.HeldSince, tt.wantPolls, tt.wantHeldSince)
+		}
+		if warning.FirstPoll() != (tt.wantPolls == 1) {
+			t.Errorf("%s: FirstPoll() = %v", tt.name, warning.FirstPoll())
+		}
+	}
+}
@@ -1,59 +1,165 @@
 package processor
 
 import (
-	"all_exchange_symbol/database"
 	"all_exchange_symbol/models"
+	"all_exchange_symbol/store"
+	"context"
+	"errors"
 	"log"
 	"sort"
+	"strings"
+	"time"
 )
 
-type Processor struct{}
+type Processor struct {
+	store store.SymbolStore
+	guard DelistGuard
+	now   func() time.Time
+}
+
+func NewProcessor(symbolStore store.SymbolStore) *Processor {
+	return &Processor{
+		store: symbolStore,
+		guard: DefaultDelistGuard(),
+		now:   time.Now,
+	}
+}
 
-func NewProcessor() *Processor {
-	return &Processor{}
+func (p *Processor) SetDelistGuard(guard DelistGuard) {
+	p.guard = guard
 }
 
-func (p *Processor) ProcessSymbols(fetchedSymbols []models.Symbol) ([]models.Symbol, error) {
+// ProcessSymbols compares fetched symbols with the store and returns new,
+// delisted and relisted symbols. Delisting is only evaluated for
+// exchange+type markets that the report marks as fetched successfully and
+// whose symbol count did not drop past the delist guard threshold, so a
+// failed or truncated response never marks a whole market as delisted.
+// Expired delivery contracts and options are left out of the guard's count
+// and returned as Expired instead of Delisted, since their disappearance is
+// expected.
+func (p *Processor) ProcessSymbols(ctx context.Context, fetchedSymbols []models.Symbol, report *models.FetchReport) (*models.SymbolChanges, error) {
 	log.Printf("=== 开始处理交易对数据 ===")
 	log.Printf("从API获取的交易对总数: %d", len(fetchedSymbols))
 
-	var newSymbols []models.Symbol
+	changes := &models.SymbolChanges{}
 	var existingCount int
 
-	exchangeCounts := make(map[string]map[string]int)
+	exchangeCounts := make(map[string]map[models.MarketType]int)
 
 	// 批量获取所有现有的交易对组合以提高性能
 	log.Printf("正在批量获取现有交易对数据...")
-	existingSymbols, err := p.GetAllExistingSymbols()
+	existingSymbols, err := p.GetAllExistingSymbols(ctx)
 	if err != nil {
 		return nil, err
 	}
 
 	// 创建一个map用于快速查找现有的交易对
-	existingCombinations := make(map[string]bool)
+	existingCombinations := make(map[string]models.Symbol)
 	for _, symbol := range existingSymbols {
-		combination := symbol.Exchange + "-" + symbol.Type + "-" + symbol.Symbol
-		existingCombinations[combination] = true
+		existingCombinations[symbol.Key()] = symbol
 	}
 	log.Printf("数据库中现有交易对总数: %d", len(existingSymbols))
 
+	fetchedCombinations := make(map[string]bool)
 	for _, symbol := range fetchedSymbols {
 		if _, ok := exchangeCounts[symbol.Exchange]; !ok {
-			exchangeCounts[symbol.Exchange] = make(map[string]int)
+			exchangeCounts[symbol.Exchange] = make(map[models.MarketType]int)
 		}
 		exchangeCounts[symbol.Exchange][symbol.Type]++
 
-		combination := symbol.Exchange + "-" + symbol.Type + "-" + symbol.Symbol
-		exists := existingCombinations[combination]
+		key := symbol.Key()
+		if fetchedCombinations[key] {
+			continue
+		}
+		fetchedCombinations[key] = true
+
+		existing, exists := existingCombinations[key]
+		if exists && symbol.MetadataChanged(&existing) {
+			changes.Updated = append(changes.Updated, symbol)
+		}
+		if !exists || !existing.IsDelisted() {
+			from := models.TradingUnknown
+			if exists {
+				from = existing.TradingStatus
+			}
+			if event, ok := models.TransitionEvent(from, symbol.TradingStatus, !exists); ok {
+				changes.Transitions = append(changes.Transitions, models.StatusTransition{
+					Symbol: symbol,
+					From:   from,
+					To:     symbol.TradingStatus,
+					Event:  event,
+				})
+				log.Printf("交易对状态变化(%s): %s-%s-%s %q -> %q", event, symbol.Exchange, symbol.Type, symbol.Symbol, from, symbol.TradingStatus)
+			}
+		}
 
 		if !exists {
-			newSymbols = append(newSymbols, symbol)
+			changes.New = append(changes.New, symbol)
 			log.Printf("发现新交易对: %s-%s-%s", symbol.Exchange, symbol.Type, symbol.Symbol)
+		} else if existing.IsDelisted() {
+			changes.Relisted = append(changes.Relisted, existing)
+			log.Printf("交易对重新上线: %s-%s-%s", symbol.Exchange, symbol.Type, symbol.Symbol)
 		} else {
 			existingCount++
 		}
 	}
 
+	now := p.now()
+	storedCounts := make(map[string]int)
+	for _, symbol := range existingSymbols {
+		if symbol.IsDelisted() || (symbol.DeliveryAt != nil && !symbol.DeliveryAt.After(now)) {
+			continue
+		}
+		storedCounts[marketKey(symbol.Exchange, symbol.Type)]++
+	}
+
+	held, err := p.guardStates(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	heldMarkets := make(map[string]bool)
+	if report != nil {
+		for _, result := range report.Results {
+			if !result.OK() {
+				continue
+			}
+			market := marketKey(result.Exchange, result.Market)
+			warning := p.guard.Check(result.Exchange, result.Market, storedCounts[market],
+				exchangeCounts[result.Exchange][result.Market], held[market], now)
+
+			state := models.GuardState{Exchange: result.Exchange, Market: result.Market}
+			if warning != nil {
+				heldMarkets[market] = true
+				changes.Warnings = append(changes.Warnings, *warning)
+				state.HeldPolls = warning.HeldPolls
+				state.HeldSince = &warning.HeldSince
+			}
+			if state.HeldPolls != held[market].HeldPolls {
+				changes.GuardStates = append(changes.GuardStates, state)
+			}
+		}
+	}
+
+	for _, symbol := range existingSymbols {
+		if symbol.IsDelisted() || !report.IsOK(symbol.Exchange, symbol.Type) {
+			continue
+		}
+		if heldMarkets[marketKey(symbol.Exchange, symbol.Type)] {
+			continue
+		}
+		if fetchedCombinations[symbol.Key()] {
+			continue
+		}
+		if symbol.DeliveryAt != nil && !symbol.DeliveryAt.After(now) {
+			changes.Expired = append(changes.Expired, symbol)
+			log.Printf("合约已到期: %s-%s-%s", symbol.Exchange, symbol.Type, symbol.Symbol)
+		} else {
+			changes.Delisted = append(changes.Delisted, symbol)
+			log.Printf("交易对已下架: %s-%s-%s", symbol.Exchange, symbol.Type, symbol.Symbol)
+		}
+	}
+
 	log.Printf("\n=== 数据处理统计 ===")
 	for exchange, types := range exchangeCounts {
 		log.Printf("交易所 %s:", exchange)
@@ -63,96 +169,92 @@ func (p *Processor) ProcessSymbols(fetchedSymbols []models.Symbol) ([]models.Sym
 	}
 
 	log.Printf("\n数据库中已存在: %d 个", existingCount)
-	log.Printf("新发现的交易对: %d 个", len(newSymbols))
+	log.Printf("新发现的交易对: %d 个", len(changes.New))
+	log.Printf("下架的交易对: %d 个", len(changes.Delisted))
+	log.Printf("到期的合约: %d 个", len(changes.Expired))
+	log.Printf("重新上线的交易对: %d 个", len(changes.Relisted))
+	log.Printf("元数据更新的交易对: %d 个", len(changes.Updated))
+	log.Printf("交易状态变化: %d 个", len(changes.Transitions))
+	if len(changes.Warnings) > 0 {
+		log.Printf("因数量骤降暂停下架处理的市场: %d 个", len(changes.Warnings))
+	}
 	log.Printf("处理完成，总处理: %d 个", len(fetchedSymbols))
 
-	return newSymbols, nil
+	return changes, nil
 }
 
-func (p *Processor) CheckSymbolExists(symbol models.Symbol) (bool, error) {
-	var existingSymbol models.Symbol
-
-	result := database.DB.Where("combination = ?", symbol.Exchange+"-"+symbol.Type+"-"+symbol.Symbol).First(&existingSymbol)
+// guardStates returns the delist guard's hold of every held market by market
+// key.
+func (p *Processor) guardStates(ctx context.Context) (map[string]models.GuardState, error) {
+	results, err := p.store.ListFetchResults(ctx)
+	if err != nil {
+		return nil, err
+	}
 
-	if result.Error != nil {
-		if result.Error.Error() == "record not found" {
-			return false, nil
+	states := make(map[string]models.GuardState)
+	for _, result := range results {
+		if result.HeldPolls > 0 {
+			states[marketKey(result.Exchange, result.Market)] = models.GuardState{
+				Exchange:  result.Exchange,
+				Market:    result.Market,
+				HeldPolls: result.HeldPolls,
+				HeldSince: result.HeldSince,
+			}
 		}
-		return false, result.Error
 	}
-
-	return true, nil
+	return states, nil
 }
 
-func (p *Processor) GetExistingSymbolsByExchange(exchange string) ([]models.Symbol, error) {
-	var symbols []models.Symbol
-
-	result := database.DB.Where("exchange = ?", exchange).Find(&symbols)
-	if result.Error != nil {
-		return nil, result.Error
-	}
-
-	return symbols, nil
+// marketKey identifies one market of one exchange, e.g. "binance-spot".
+func marketKey(exchange string, market models.MarketType) string {
+	return exchange + "-" + string(market)
 }
 
-func (p *Processor) GetExistingSymbolsByType(symbolType string) ([]models.Symbol, error) {
-	var symbols []models.Symbol
-
-	result := database.DB.Where("type = ?", symbolType).Find(&symbols)
-	if result.Error != nil {
-		return nil, result.Error
+func (p *Processor) CheckSymbolExists(ctx context.Context, symbol models.Symbol) (bool, error) {
+	_, err := p.store.FindByCombination(ctx, symbol.Key())
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			return false, nil
+		}
+		return false, err
 	}
 
-	return symbols, nil
+	return true, nil
 }
 
-func (p *Processor) GetAllExistingSymbols() ([]models.Symbol, error) {
-	var symbols []models.Symbol
-
-	result := database.DB.Find(&symbols)
-	if result.Error != nil {
-		return nil, result.Error
-	}
-
-	return symbols, nil
+func (p *Processor) GetExistingSymbolsByExchange(ctx context.Context, exchange string) ([]models.Symbol, error) {
+	return p.store.ListByExchange(ctx, exchange)
 }
 
-func (p *Processor) GetSymbolCount() (int64, error) {
-	var count int64
-
-	result := database.DB.Model(&models.Symbol{}).Count(&count)
-	if result.Error != nil {
-		return 0, result.Error
-	}
-
-	return count, nil
+func (p *Processor) GetExistingSymbolsByType(ctx context.Context, symbolType models.MarketType) ([]models.Symbol, error) {
+	return p.store.ListByType(ctx, symbolType)
 }
 
-func (p *Processor) GetSymbolCountByExchange(exchange string) (int64, error) {
-	var count int64
-
-	result := database.DB.Model(&models.Symbol{}).Where("exchange = ?", exchange).Count(&count)
-	if result.Error != nil {
-		return 0, result.Error
-	}
+// GetSymbolsByCanonical lists the same market across exchanges, e.g. every
+// listing of BTC/USDT:USDT.
+func (p *Processor) GetSymbolsByCanonical(ctx context.Context, canonical string) ([]models.Symbol, error) {
+	return p.store.ListByCanonical(ctx, strings.ToUpper(canonical))
+}
 
-	return count, nil
+func (p *Processor) GetAllExistingSymbols(ctx context.Context) ([]models.Symbol, error) {
+	return p.store.ListAll(ctx)
 }
 
-func (p *Processor) getSymbolsByExchangeAndType(exchange, symbolType string) ([]models.Symbol, error) {
-	var symbols []models.Symbol
+func (p *Processor) GetSymbolCount(ctx context.Context) (int64, error) {
+	return p.store.Count(ctx)
+}
 
-	result := database.DB.Where("exchange = ? AND type = ?", exchange, symbolType).Find(&symbols)
-	if result.Error != nil {
-		return nil, result.Error
-	}
+func (p *Processor) GetSymbolCountByExchange(ctx context.Context, exchange string) (int64, error) {
+	return p.store.CountByExchange(ctx, exchange)
+}
 
-	return symbols, nil
+func (p *Processor) getSymbolsByExchangeAndType(ctx context.Context, exchange string, symbolType models.MarketType) ([]models.Symbol, error) {
+	return p.store.ListByExchangeAndType(ctx, exchange, symbolType)
 }
 
 type DataComparisonResult struct {
 	Exchange      string
-	Type          string
+	Type          models.MarketType
 	APICount      int
 	DBCount       int
 	NewInAPI      []string
@@ -160,11 +262,11 @@ type DataComparisonResult struct {
 	CommonSymbols []string
 }
 
-func (p *Processor) CompareAPIWithDatabase(apiSymbols []models.Symbol, exchange, symbolType string) (*DataComparisonResult, error) {
+func (p *Processor) CompareAPIWithDatabase(ctx context.Context, apiSymbols []models.Symbol, exchange string, symbolType models.MarketType) (*DataComparisonResult, error) {
 	log.Printf("=== 开始对比%s %s数据 ===", exchange, symbolType)
 	log.Printf("API获取到 %d 个交易对", len(apiSymbols))
 
-	dbSymbols, err := p.getSymbolsByExchangeAndType(exchange, symbolType)
+	dbSymbols, err := p.getSymbolsByExchangeAndType(ctx, exchange, symbolType)
 	if err != nil {
 		log.Printf("获取数据库中%s %s交易对失败: %v", exchange, symbolType, err)
 		return nil, err
@@ -180,6 +282,9 @@ func (p *Processor) CompareAPIWithDatabase(apiSymbols []models.Symbol, exchange,
 	}
 
 	for _, symbol := range dbSymbols {
+		if symbol.IsDelisted() {
+			continue
+		}
 		dbSet[symbol.Symbol] = true
 	}
 
@@ -209,7 +314,7 @@ func (p *Processor) CompareAPIWithDatabase(apiSymbols []models.Symbol, exchange,
 		Exchange:      exchange,
 		Type:          symbolType,
 		APICount:      len(apiSymbols),
-		DBCount:       len(dbSymbols),
+		DBCount:       len(dbSet),
 		NewInAPI:      newInAPI,
 		MissingInAPI:  missingInAPI,
 		CommonSymbols: common,
@@ -0,0 +1,77 @@
+package processor
+
+import (
+	"all_exchange_symbol/models"
+	"all_exchange_symbol/store"
+	"context"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func option(name string, deliveryAt time.Time) models.Symbol {
+	return models.Symbol{
+		Exchange:   "okx",
+		Type:       models.MarketOption,
+		Symbol:     name,
+		DeliveryAt: &deliveryAt,
+	}
+}
+
+func TestProcessSymbolsExpiredOptions(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2025, 3, 28, 8, 30, 0, 0, time.UTC)
+	expiry := time.Date(2025, 3, 28, 8, 0, 0, 0, time.UTC)
+	nextExpiry := expiry.AddDate(0, 0, 7)
+
+	// a daily expiry removes most of the market at once
+	var stored, fetched []models.Symbol
+	for i := 0; i < 100; i++ {
+		stored = append(stored, option(fmt.Sprintf("BTC-USD-250328-%d-C", 80000+i*100), expiry))
+	}
+	for i := 0; i < 30; i++ {
+		symbol := option(fmt.Sprintf("BTC-USD-250404-%d-C", 80000+i*100), nextExpiry)
+		stored = append(stored, symbol)
+		if i > 0 {
+			fetched = append(fetched, symbol)
+		}
+	}
+
+	symbolStore := store.NewMemoryStore()
+	if err := symbolStore.CreateBatch(ctx, stored); err != nil {
+		t.Fatalf("CreateBatch: %v", err)
+	}
+
+	p := NewProcessor(symbolStore)
+	p.now = func() time.Time { return now }
+
+	report := &models.FetchReport{}
+	report.Add("okx", models.MarketOption, len(fetched), time.Second, nil)
+
+	changes, err := p.ProcessSymbols(ctx, fetched, report)
+	if err != nil {
+		t.Fatalf("ProcessSymbols: %v", err)
+	}
+
+	if len(changes.Expired) != 100 {
+		t.Fatalf("%d expired options, want 100", len(changes.Expired))
+	}
+	if len(changes.Delisted) != 1 || changes.Delisted[0].Symbol != "BTC-USD-250404-80000-C" {
+		t.Errorf("delisted %v, want only BTC-USD-250404-80000-C", changes.Delisted)
+	}
+	if len(changes.Warnings) != 0 {
+		t.Errorf("expiry tripped the delist guard: %+v", changes.Warnings)
+	}
+
+	// expired contracts are stored as delisted like any other
+	if err := symbolStore.ApplyChanges(ctx, changes, now, nil); err != nil {
+		t.Fatalf("ApplyChanges: %v", err)
+	}
+	expired, err := symbolStore.FindByCombination(ctx, changes.Expired[0].Key())
+	if err != nil {
+		t.Fatalf("FindByCombination: %v", err)
+	}
+	if !expired.IsDelisted() {
+		t.Errorf("expired option %s not marked as delisted", expired.Symbol)
+	}
+}
@@ -3,106 +3,158 @@ package reader
 import (
 	"all_exchange_symbol/exchanges"
 	"all_exchange_symbol/models"
+	"all_exchange_symbol/normalizer"
+	"context"
+	"fmt"
 	"log"
+	"sort"
 	"sync"
+	"time"
 )
 
 type Reader struct {
 	exchanges []exchanges.ExchangeInterface
 }
 
-func NewReader() *Reader {
-	return &Reader{
-		exchanges: []exchanges.ExchangeInterface{
-			exchanges.NewBinance(),
-			exchanges.NewOKX(),
-			exchanges.NewGate(),
-			exchanges.NewBitget(),
-			exchanges.NewBybit(),
-		},
-	}
+func NewReader(exs []exchanges.ExchangeInterface) *Reader {
+	return &Reader{exchanges: exs}
 }
 
-func (r *Reader) FetchAllSymbols() ([]models.Symbol, error) {
-	var allSymbols []models.Symbol
-	var mu sync.Mutex
-	var wg sync.WaitGroup
-	errorChan := make(chan error, len(r.exchanges)*2)
-
+// ExchangeNames returns the names of the exchanges this reader polls.
+func (r *Reader) ExchangeNames() []string {
+	names := make([]string, 0, len(r.exchanges))
 	for _, exchange := range r.exchanges {
-		wg.Add(2)
-
-		go func(ex exchanges.ExchangeInterface) {
-			defer wg.Done()
-			symbols, err := ex.FetchSpotSymbols()
-			if err != nil {
-				log.Printf("Error fetching spot symbols from %s: %v", ex.GetName(), err)
-				errorChan <- err
-				return
-			}
+		names = append(names, exchange.GetName())
+	}
+	return names
+}
 
-			mu.Lock()
-			allSymbols = append(allSymbols, symbols...)
-			mu.Unlock()
+type marketFetcher struct {
+	market models.MarketType
+	fetch  func(ctx context.Context) ([]models.Symbol, error)
+}
 
-			log.Printf("Successfully fetched %d spot symbols from %s", len(symbols), ex.GetName())
-		}(exchange)
+func marketFetchers(ex exchanges.ExchangeInterface) []marketFetcher {
+	markets := ex.SupportedMarkets()
+	fetchers := make([]marketFetcher, 0, len(markets))
+	for _, market := range markets {
+		market := market
+		fetchers = append(fetchers, marketFetcher{
+			market: market,
+			fetch: normalized(func(ctx context.Context) ([]models.Symbol, error) {
+				return ex.FetchSymbols(ctx, market)
+			}),
+		})
+	}
+	return fetchers
+}
 
-		go func(ex exchanges.ExchangeInterface) {
-			defer wg.Done()
-			symbols, err := ex.FetchFuturesSymbols()
-			if err != nil {
-				log.Printf("Error fetching futures symbols from %s: %v", ex.GetName(), err)
-				errorChan <- err
-				return
-			}
+// normalized wraps an adapter fetch so every symbol carries its canonical id.
+func normalized(fetch func(ctx context.Context) ([]models.Symbol, error)) func(ctx context.Context) ([]models.Symbol, error) {
+	return func(ctx context.Context) ([]models.Symbol, error) {
+		symbols, err := fetch(ctx)
+		if err != nil {
+			return nil, err
+		}
+		normalizer.Apply(symbols)
+		return symbols, nil
+	}
+}
 
-			mu.Lock()
-			allSymbols = append(allSymbols, symbols...)
-			mu.Unlock()
+// FetchAllSymbols fetches every market of every exchange concurrently. Failed
+// markets are recorded in the report; an error is only returned when nothing
+// could be fetched at all.
+func (r *Reader) FetchAllSymbols(ctx context.Context) ([]models.Symbol, *models.FetchReport, error) {
+	var allSymbols []models.Symbol
+	report := &models.FetchReport{}
+	var mu sync.Mutex
+	var wg sync.WaitGroup
 
-			log.Printf("Successfully fetched %d futures symbols from %s", len(symbols), ex.GetName())
-		}(exchange)
+	for _, exchange := range r.exchanges {
+		for _, fetcher := range marketFetchers(exchange) {
+			wg.Add(1)
+
+			go func(ex exchanges.ExchangeInterface, f marketFetcher) {
+				defer wg.Done()
+				start := time.Now()
+				symbols, err := f.fetch(ctx)
+				latency := time.Since(start)
+
+				mu.Lock()
+				defer mu.Unlock()
+
+				report.Add(ex.GetName(), f.market, len(symbols), latency, err)
+				if err != nil {
+					log.Printf("Error fetching %s symbols from %s: %v", f.market, ex.GetName(), err)
+					return
+				}
+
+				allSymbols = append(allSymbols, symbols...)
+				log.Printf("Successfully fetched %d %s symbols from %s in %v", len(symbols), f.market, ex.GetName(), latency)
+			}(exchange, fetcher)
+		}
 	}
 
 	wg.Wait()
-	close(errorChan)
+	sortReport(report)
 
-	var errors []error
-	for err := range errorChan {
-		errors = append(errors, err)
+	if err := ctx.Err(); err != nil {
+		return nil, report, err
 	}
 
-	if len(errors) > 0 {
-		log.Printf("Encountered %d errors during fetching, but continuing with available data", len(errors))
+	failed := report.Failed()
+	if len(failed) > 0 {
+		log.Printf("Encountered %d errors during fetching, but continuing with available data", len(failed))
+	}
+	if len(report.Results) > 0 && len(failed) == len(report.Results) {
+		return nil, report, fmt.Errorf("all %d market fetches failed", len(failed))
 	}
 
 	log.Printf("Total symbols fetched: %d", len(allSymbols))
-	return allSymbols, nil
+	return allSymbols, report, nil
 }
 
-func (r *Reader) FetchSymbolsByExchange(exchangeName string) ([]models.Symbol, error) {
+func (r *Reader) FetchSymbolsByExchange(ctx context.Context, exchangeName string) ([]models.Symbol, *models.FetchReport, error) {
 	for _, exchange := range r.exchanges {
 		if exchange.GetName() == exchangeName {
 			var allSymbols []models.Symbol
-
-			spotSymbols, err := exchange.FetchSpotSymbols()
-			if err != nil {
-				log.Printf("Error fetching spot symbols from %s: %v", exchangeName, err)
-			} else {
-				allSymbols = append(allSymbols, spotSymbols...)
+			report := &models.FetchReport{}
+
+			for _, fetcher := range marketFetchers(exchange) {
+				if err := ctx.Err(); err != nil {
+					return nil, report, err
+				}
+
+				start := time.Now()
+				symbols, err := fetcher.fetch(ctx)
+				report.Add(exchangeName, fetcher.market, len(symbols), time.Since(start), err)
+				if err != nil {
+					log.Printf("Error fetching %s symbols from %s: %v", fetcher.market, exchangeName, err)
+					continue
+				}
+				allSymbols = append(allSymbols, symbols...)
 			}
 
-			futuresSymbols, err := exchange.FetchFuturesSymbols()
-			if err != nil {
-				log.Printf("Error fetching futures symbols from %s: %v", exchangeName, err)
-			} else {
-				allSymbols = append(allSymbols, futuresSymbols...)
+			if len(report.Failed()) == len(report.Results) {
+				return nil, report, fmt.Errorf("all markets of %s failed to fetch", exchangeName)
 			}
 
-			return allSymbols, nil
+			return allSymbols, report, nil
 		}
 	}
 
-	return nil, nil
+	if _, err := exchanges.DefaultRegistry.Get(exchangeName); err != nil {
+		return nil, nil, err
+	}
+	return nil, nil, fmt.Errorf("exchange %s is disabled by configuration", exchangeName)
+}
+
+func sortReport(report *models.FetchReport) {
+	sort.Slice(report.Results, func(i, j int) bool {
+		a, b := report.Results[i], report.Results[j]
+		if a.Exchange != b.Exchange {
+			return a.Exchange < b.Exchange
+		}
+		return a.Market < b.Market
+	})
 }
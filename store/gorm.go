@@ -0,0 +1,452 @@
+package store
+
+import (
+	"all_exchange_symbol/models"
+	"context"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// updateChunkSize keeps IN (...) lists well below driver placeholder limits.
+const updateChunkSize = 500
+
+// insertBatchSize is the number of rows per INSERT. A symbol row has 24
+// columns, so 500 rows stay well below SQLite's 32,766 and MySQL's 65,535
+// placeholders per statement.
+const insertBatchSize = 500
+
+type GormStore struct {
+	db *gorm.DB
+}
+
+func NewGormStore(db *gorm.DB) *GormStore {
+	return &GormStore{db: db}
+}
+
+func (s *GormStore) FindByCombination(ctx context.Context, combination string) (*models.Symbol, error) {
+	var symbol models.Symbol
+
+	result := s.db.WithContext(ctx).Where("combination = ?", combination).First(&symbol)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, result.Error
+	}
+
+	return &symbol, nil
+}
+
+func (s *GormStore) ListAll(ctx context.Context) ([]models.Symbol, error) {
+	var symbols []models.Symbol
+
+	result := s.db.WithContext(ctx).Find(&symbols)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	return symbols, nil
+}
+
+func (s *GormStore) ListByExchange(ctx context.Context, exchange string) ([]models.Symbol, error) {
+	var symbols []models.Symbol
+
+	result := s.db.WithContext(ctx).Where("exchange = ?", exchange).Find(&symbols)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	return symbols, nil
+}
+
+func (s *GormStore) ListByType(ctx context.Context, symbolType models.MarketType) ([]models.Symbol, error) {
+	var symbols []models.Symbol
+
+	result := s.db.WithContext(ctx).Where("type = ?", symbolType).Find(&symbols)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	return symbols, nil
+}
+
+func (s *GormStore) ListByExchangeAndType(ctx context.Context, exchange string, symbolType models.MarketType) ([]models.Symbol, error) {
+	var symbols []models.Symbol
+
+	result := s.db.WithContext(ctx).Where("exchange = ? AND type = ?", exchange, symbolType).Find(&symbols)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	return symbols, nil
+}
+
+func (s *GormStore) ListByCanonical(ctx context.Context, canonical string) ([]models.Symbol, error) {
+	var symbols []models.Symbol
+
+	result := s.db.WithContext(ctx).Where("canonical = ?", canonical).Order("exchange, type").Find(&symbols)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	return symbols, nil
+}
+
+func (s *GormStore) Count(ctx context.Context) (int64, error) {
+	var count int64
+
+	result := s.db.WithContext(ctx).Model(&models.Symbol{}).Count(&count)
+	if result.Error != nil {
+		return 0, result.Error
+	}
+
+	return count, nil
+}
+
+func (s *GormStore) CountByExchange(ctx context.Context, exchange string) (int64, error) {
+	var count int64
+
+	result := s.db.WithContext(ctx).Model(&models.Symbol{}).Where("exchange = ?", exchange).Count(&count)
+	if result.Error != nil {
+		return 0, result.Error
+	}
+
+	return count, nil
+}
+
+func (s *GormStore) CreateBatch(ctx context.Context, symbols []models.Symbol) error {
+	if len(symbols) == 0 {
+		return nil
+	}
+
+	return s.CreateBatchWithNotifications(ctx, symbols, nil)
+}
+
+func (s *GormStore) CreateBatchWithNotifications(ctx context.Context, symbols []models.Symbol, notifications []models.Notification) error {
+	if len(symbols) == 0 && len(notifications) == 0 {
+		return nil
+	}
+
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return createRows(tx, symbols, notifications)
+	})
+}
+
+func createRows(tx *gorm.DB, symbols []models.Symbol, notifications []models.Notification) error {
+	if len(symbols) > 0 {
+		if err := tx.CreateInBatches(&symbols, insertBatchSize).Error; err != nil {
+			return err
+		}
+	}
+	if len(notifications) > 0 {
+		if err := tx.CreateInBatches(&notifications, insertBatchSize).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ApplyChanges writes everything one poll detected in a single transaction:
+// new symbols, delistings and expiries at delistedAt, relistings, metadata,
+// the delist guard's hold and the outbox messages announcing them.
+func (s *GormStore) ApplyChanges(ctx context.Context, changes *models.SymbolChanges, delistedAt time.Time, notifications []models.Notification) error {
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := updateStatus(tx, symbolKeys(changes.Delisted), delistedValues(delistedAt)); err != nil {
+			return err
+		}
+		if err := updateStatus(tx, symbolKeys(changes.Expired), delistedValues(delistedAt)); err != nil {
+			return err
+		}
+		if err := updateStatus(tx, symbolKeys(changes.Relisted), relistedValues()); err != nil {
+			return err
+		}
+		if err := updateMetadata(tx, changes.Updated); err != nil {
+			return err
+		}
+		if err := updateGuardStates(tx, changes.GuardStates); err != nil {
+			return err
+		}
+		return createRows(tx, changes.New, notifications)
+	})
+}
+
+func symbolKeys(symbols []models.Symbol) []string {
+	keys := make([]string, 0, len(symbols))
+	for _, symbol := range symbols {
+		keys = append(keys, symbol.Key())
+	}
+	return keys
+}
+
+func (s *GormStore) MarkDelisted(ctx context.Context, combinations []string, at time.Time) error {
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return updateStatus(tx, combinations, delistedValues(at))
+	})
+}
+
+func (s *GormStore) MarkRelisted(ctx context.Context, combinations []string) error {
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return updateStatus(tx, combinations, relistedValues())
+	})
+}
+
+func delistedValues(at time.Time) map[string]interface{} {
+	return map[string]interface{}{
+		"status":      models.StatusDelisted,
+		"delisted_at": at,
+	}
+}
+
+func relistedValues() map[string]interface{} {
+	return map[string]interface{}{
+		"status":      models.StatusActive,
+		"delisted_at": nil,
+	}
+}
+
+func updateStatus(tx *gorm.DB, combinations []string, values map[string]interface{}) error {
+	for start := 0; start < len(combinations); start += updateChunkSize {
+		end := start + updateChunkSize
+		if end > len(combinations) {
+			end = len(combinations)
+		}
+
+		result := tx.Model(&models.Symbol{}).Where("combination IN ?", combinations[start:end]).Updates(values)
+		if result.Error != nil {
+			return result.Error
+		}
+	}
+	return nil
+}
+
+// UpdateMetadata overwrites the instrument metadata of existing symbols,
+// matched by combination.
+func (s *GormStore) UpdateMetadata(ctx context.Context, symbols []models.Symbol) error {
+	if len(symbols) == 0 {
+		return nil
+	}
+
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return updateMetadata(tx, symbols)
+	})
+}
+
+func updateMetadata(tx *gorm.DB, symbols []models.Symbol) error {
+	for _, symbol := range symbols {
+		result := tx.Model(&models.Symbol{}).
+			Where("combination = ?", symbol.Key()).
+			Select("canonical", "base_asset", "quote_asset", "settle_asset", "exchange_status", "trading_status", "tick_size", "lot_size",
+				"min_qty", "min_notional", "price_precision", "quantity_precision", "contract_size", "contract_type",
+				"delisting_scheduled", "delivery_at").
+			Updates(&models.Symbol{InstrumentInfo: symbol.InstrumentInfo, DeliveryAt: symbol.DeliveryAt})
+		if result.Error != nil {
+			return result.Error
+		}
+	}
+	return nil
+}
+
+func (s *GormStore) UpdateListedAt(ctx context.Context, symbols []models.Symbol) (int, error) {
+	updated := 0
+
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		for _, symbol := range symbols {
+			if symbol.ListedAt == nil {
+				continue
+			}
+			result := tx.Model(&models.Symbol{}).
+				Where("combination = ? AND (listed_at IS NULL OR listed_at <> ?)", symbol.Key(), *symbol.ListedAt).
+				Update("listed_at", *symbol.ListedAt)
+			if result.Error != nil {
+				return result.Error
+			}
+			updated += int(result.RowsAffected)
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return updated, nil
+}
+
+// SaveFetchResults upserts the latest result for each exchange+market.
+func (s *GormStore) SaveFetchResults(ctx context.Context, results []models.MarketFetchResult) error {
+	if len(results) == 0 {
+		return nil
+	}
+
+	rows := make([]models.MarketFetchResult, len(results))
+	copy(rows, results)
+	for i := range rows {
+		rows[i].ID = 0
+	}
+
+	return s.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "exchange"}, {Name: "market"}},
+		DoUpdates: clause.AssignmentColumns([]string{"status", "error_message", "symbol_count", "latency", "fetched_at"}),
+	}).Create(&rows).Error
+}
+
+func (s *GormStore) ListFetchResults(ctx context.Context) ([]models.MarketFetchResult, error) {
+	var results []models.MarketFetchResult
+
+	result := s.db.WithContext(ctx).Order("exchange, market").Find(&results)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	return results, nil
+}
+
+// updateGuardStates stores the delist guard's hold on the fetch result of
+// each market.
+func updateGuardStates(tx *gorm.DB, states []models.GuardState) error {
+	for _, state := range states {
+		result := tx.Model(&models.MarketFetchResult{}).
+			Where("exchange = ? AND market = ?", state.Exchange, state.Market).
+			Updates(map[string]interface{}{"held_polls": state.HeldPolls, "held_since": state.HeldSince})
+		if result.Error != nil {
+			return result.Error
+		}
+	}
+	return nil
+}
+
+func (s *GormStore) EnqueueNotifications(ctx context.Context, notifications []models.Notification) error {
+	return s.CreateBatchWithNotifications(ctx, nil, notifications)
+}
+
+func (s *GormStore) ListPendingNotifications(ctx context.Context, channel string, limit int) ([]models.Notification, error) {
+	var notifications []models.Notification
+
+	result := s.db.WithContext(ctx).
+		Where("channel = ? AND status = ?", channel, models.NotificationPending).
+		Order("id").Limit(limit).Find(&notifications)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	return notifications, nil
+}
+
+func (s *GormStore) CountPendingNotifications(ctx context.Context, now time.Time) (int64, error) {
+	var count int64
+
+	result := s.db.WithContext(ctx).Model(&models.Notification{}).
+		Where("status = ? AND (attempts > 0 OR next_attempt_at <= ?)", models.NotificationPending, now).
+		Count(&count)
+	if result.Error != nil {
+		return 0, result.Error
+	}
+
+	return count, nil
+}
+
+func (s *GormStore) UpdateNotification(ctx context.Context, notification models.Notification) error {
+	return s.db.WithContext(ctx).Model(&models.Notification{}).
+		Where("id = ?", notification.ID).
+		Select("status", "attempts", "next_attempt_at", "last_error", "sent_at").
+		Updates(&notification).Error
+}
+
+func (s *GormStore) DeleteNotifications(ctx context.Context, before time.Time) (int64, error) {
+	result := s.db.WithContext(ctx).
+		Where("status IN ? AND created_at < ?", []string{models.NotificationSent, models.NotificationFailed}, before).
+		Delete(&models.Notification{})
+	if result.Error != nil {
+		return 0, result.Error
+	}
+
+	return result.RowsAffected, nil
+}
+
+// MigrateLegacyFutures reclassifies rows stored with the old "futures" type
+// and rewrites their combination accordingly. classify receives each legacy
+// row and returns its market type.
+func (s *GormStore) MigrateLegacyFutures(ctx context.Context, classify func(*models.Symbol) models.MarketType) (int, error) {
+	var legacy []models.Symbol
+
+	result := s.db.WithContext(ctx).Where("type = ?", models.LegacyFuturesType).Find(&legacy)
+	if result.Error != nil {
+		return 0, result.Error
+	}
+	if len(legacy) == 0 {
+		return 0, nil
+	}
+
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		for i := range legacy {
+			symbol := &legacy[i]
+			symbol.Type = classify(symbol)
+			result := tx.Model(&models.Symbol{}).Where("id = ?", symbol.ID).Updates(map[string]interface{}{
+				"type":        symbol.Type,
+				"combination": symbol.Key(),
+			})
+			if result.Error != nil {
+				return result.Error
+			}
+		}
+
+		// the per-market fetch status of "futures" no longer maps to a market
+		return tx.Where("market = ?", models.LegacyFuturesType).Delete(&models.MarketFetchResult{}).Error
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return len(legacy), nil
+}
+
+// RenameSymbols rewrites the symbol names of one exchange, e.g. after an API
+// version change, keeping each row's history. Rows whose new name already
+// exists are dropped in favor of the existing row.
+func (s *GormStore) RenameSymbols(ctx context.Context, exchange string, rename func(string) string) (int, error) {
+	var symbols []models.Symbol
+
+	result := s.db.WithContext(ctx).Where("exchange = ?", exchange).Find(&symbols)
+	if result.Error != nil {
+		return 0, result.Error
+	}
+
+	renamed := 0
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		for i := range symbols {
+			symbol := &symbols[i]
+			name := rename(symbol.Symbol)
+			if name == symbol.Symbol {
+				continue
+			}
+			symbol.Symbol = name
+
+			var existing int64
+			if err := tx.Model(&models.Symbol{}).Where("combination = ?", symbol.Key()).Count(&existing).Error; err != nil {
+				return err
+			}
+			if existing > 0 {
+				if err := tx.Delete(&models.Symbol{}, symbol.ID).Error; err != nil {
+					return err
+				}
+			} else {
+				result := tx.Model(&models.Symbol{}).Where("id = ?", symbol.ID).Updates(map[string]interface{}{
+					"symbol":      symbol.Symbol,
+					"combination": symbol.Key(),
+				})
+				if result.Error != nil {
+					return result.Error
+				}
+			}
+			renamed++
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return renamed, nil
+}
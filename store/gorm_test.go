@@ -0,0 +1,200 @@
+package store
+
+import (
+	"all_exchange_symbol/models"
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func newTestGormStore(t *testing.T) *GormStore {
+	t.Helper()
+
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatalf("open sqlite: %v", err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatalf("get sqlite instance: %v", err)
+	}
+	// every connection to ":memory:" is a separate database
+	sqlDB.SetMaxOpenConns(1)
+	t.Cleanup(func() { sqlDB.Close() })
+
+	if err := db.AutoMigrate(&models.Symbol{}, &models.MarketFetchResult{}, &models.Notification{}); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	return NewGormStore(db)
+}
+
+func TestGormStoreCreateBatchManyRows(t *testing.T) {
+	s := newTestGormStore(t)
+	ctx := context.Background()
+
+	const count = 5000
+	symbols := make([]models.Symbol, 0, count)
+	for i := 0; i < count; i++ {
+		symbols = append(symbols, models.Symbol{
+			Exchange: "okx",
+			Type:     models.MarketOption,
+			Symbol:   fmt.Sprintf("BTC-USD-250328-%d-C", 10000+i),
+		})
+	}
+	notifications := models.NewNotifications("telegram", make([]string, count))
+	for i := range notifications {
+		notifications[i].Text = fmt.Sprintf("message %d", i)
+	}
+
+	if err := s.CreateBatchWithNotifications(ctx, symbols, notifications); err != nil {
+		t.Fatalf("CreateBatchWithNotifications: %v", err)
+	}
+
+	stored, err := s.CountByExchange(ctx, "okx")
+	if err != nil {
+		t.Fatalf("CountByExchange: %v", err)
+	}
+	if stored != count {
+		t.Errorf("stored %d symbols, want %d", stored, count)
+	}
+
+	pending, err := s.CountPendingNotifications(ctx, time.Now())
+	if err != nil {
+		t.Fatalf("CountPendingNotifications: %v", err)
+	}
+	if pending != count {
+		t.Errorf("queued %d notifications, want %d", pending, count)
+	}
+}
+
+func TestGormStoreCreateBatchIsAtomic(t *testing.T) {
+	s := newTestGormStore(t)
+	ctx := context.Background()
+
+	symbols := make([]models.Symbol, 0, 1200)
+	for i := 0; i < 1200; i++ {
+		symbols = append(symbols, models.Symbol{Exchange: "gate", Type: models.MarketSpot, Symbol: fmt.Sprintf("T%d_USDT", i)})
+	}
+	// a duplicate in the last batch must roll back the batches before it
+	symbols = append(symbols, symbols[0])
+
+	if err := s.CreateBatch(ctx, symbols); err == nil {
+		t.Fatal("CreateBatch with a duplicate combination succeeded")
+	}
+
+	stored, err := s.CountByExchange(ctx, "gate")
+	if err != nil {
+		t.Fatalf("CountByExchange: %v", err)
+	}
+	if stored != 0 {
+		t.Errorf("%d symbols left after a failed batch, want 0", stored)
+	}
+}
+
+func TestGormStoreApplyChangesIsAtomic(t *testing.T) {
+	s := newTestGormStore(t)
+	ctx := context.Background()
+
+	existing := models.Symbol{Exchange: "binance", Type: models.MarketSpot, Symbol: "BTCUSDT"}
+	if err := s.CreateBatch(ctx, []models.Symbol{existing}); err != nil {
+		t.Fatalf("CreateBatch: %v", err)
+	}
+
+	// the new symbol collides with the stored one, so the delisting and the
+	// alerts queued with it must not be written either
+	changes := &models.SymbolChanges{
+		New:      []models.Symbol{existing},
+		Delisted: []models.Symbol{existing},
+	}
+	notifications := models.NewNotifications("telegram", []string{"delisted BTCUSDT"})
+	if err := s.ApplyChanges(ctx, changes, time.Now(), notifications); err == nil {
+		t.Fatal("ApplyChanges with a duplicate new symbol succeeded")
+	}
+
+	stored, err := s.FindByCombination(ctx, existing.Key())
+	if err != nil {
+		t.Fatalf("FindByCombination: %v", err)
+	}
+	if stored.IsDelisted() {
+		t.Error("delisting was written although the transaction failed")
+	}
+	if pending, _ := s.CountPendingNotifications(ctx, time.Now()); pending != 0 {
+		t.Errorf("%d notifications queued although the transaction failed", pending)
+	}
+
+	changes = &models.SymbolChanges{Delisted: []models.Symbol{existing}}
+	if err := s.ApplyChanges(ctx, changes, time.Now(), notifications); err != nil {
+		t.Fatalf("ApplyChanges: %v", err)
+	}
+	stored, err = s.FindByCombination(ctx, existing.Key())
+	if err != nil {
+		t.Fatalf("FindByCombination: %v", err)
+	}
+	if !stored.IsDelisted() {
+		t.Error("symbol not marked as delisted")
+	}
+	if pending, _ := s.CountPendingNotifications(ctx, time.Now()); pending != 1 {
+		t.Errorf("%d notifications queued, want 1", pending)
+	}
+}
+
+func TestGormStoreCountPendingNotificationsSkipsScheduled(t *testing.T) {
+	s := newTestGormStore(t)
+	ctx := context.Background()
+	now := time.Now()
+
+	notifications := models.NewNotifications("email", []string{"due", "digest", "retry"})
+	notifications[1].NextAttemptAt = now.Add(time.Hour)
+	notifications[2].NextAttemptAt = now.Add(time.Hour)
+	notifications[2].Attempts = 1
+	if err := s.EnqueueNotifications(ctx, notifications); err != nil {
+		t.Fatalf("EnqueueNotifications: %v", err)
+	}
+
+	pending, err := s.CountPendingNotifications(ctx, time.Now())
+	if err != nil {
+		t.Fatalf("CountPendingNotifications: %v", err)
+	}
+	if pending != 2 {
+		t.Errorf("counted %d pending notifications, want 2 (the digest is scheduled)", pending)
+	}
+}
+
+func TestGormStoreDeleteNotificationsKeepsPending(t *testing.T) {
+	s := newTestGormStore(t)
+	ctx := context.Background()
+	now := time.Now()
+
+	notifications := models.NewNotifications("telegram", []string{"sent", "failed", "pending", "recent"})
+	for i := range notifications[:3] {
+		notifications[i].CreatedAt = now.Add(-48 * time.Hour)
+	}
+	notifications[0].Status = models.NotificationSent
+	notifications[1].Status = models.NotificationFailed
+	notifications[3].Status = models.NotificationSent
+	if err := s.EnqueueNotifications(ctx, notifications); err != nil {
+		t.Fatalf("EnqueueNotifications: %v", err)
+	}
+
+	deleted, err := s.DeleteNotifications(ctx, now.Add(-24*time.Hour))
+	if err != nil {
+		t.Fatalf("DeleteNotifications: %v", err)
+	}
+	if deleted != 2 {
+		t.Errorf("deleted %d notifications, want the old sent and failed ones", deleted)
+	}
+
+	var left []string
+	if err := s.db.Model(&models.Notification{}).Order("id").Pluck("text", &left).Error; err != nil {
+		t.Fatalf("list notifications: %v", err)
+	}
+	if strings.Join(left, ",") != "pending,recent" {
+		t.Errorf("notifications left: %v, want [pending recent]", left)
+	}
+}
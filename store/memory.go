@@ -0,0 +1,429 @@
+package store
+
+import (
+	"all_exchange_symbol/models"
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// MemoryStore keeps symbols in a map keyed by combination. It mirrors the
+// unique combination constraint of the database and is safe for concurrent use.
+type MemoryStore struct {
+	mu                 sync.RWMutex
+	nextID             uint
+	nextNotificationID uint
+	symbols            map[string]models.Symbol
+	fetchResults       map[string]models.MarketFetchResult
+	notifications      []models.Notification // in ID order
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		nextID:             1,
+		nextNotificationID: 1,
+		symbols:            make(map[string]models.Symbol),
+		fetchResults:       make(map[string]models.MarketFetchResult),
+	}
+}
+
+func (s *MemoryStore) FindByCombination(ctx context.Context, combination string) (*models.Symbol, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	symbol, ok := s.symbols[combination]
+	if !ok {
+		return nil, ErrNotFound
+	}
+
+	return &symbol, nil
+}
+
+func (s *MemoryStore) ListAll(ctx context.Context) ([]models.Symbol, error) {
+	return s.filter(func(models.Symbol) bool { return true }), nil
+}
+
+func (s *MemoryStore) ListByExchange(ctx context.Context, exchange string) ([]models.Symbol, error) {
+	return s.filter(func(symbol models.Symbol) bool {
+		return symbol.Exchange == exchange
+	}), nil
+}
+
+func (s *MemoryStore) ListByType(ctx context.Context, symbolType models.MarketType) ([]models.Symbol, error) {
+	return s.filter(func(symbol models.Symbol) bool {
+		return symbol.Type == symbolType
+	}), nil
+}
+
+func (s *MemoryStore) ListByExchangeAndType(ctx context.Context, exchange string, symbolType models.MarketType) ([]models.Symbol, error) {
+	return s.filter(func(symbol models.Symbol) bool {
+		return symbol.Exchange == exchange && symbol.Type == symbolType
+	}), nil
+}
+
+func (s *MemoryStore) ListByCanonical(ctx context.Context, canonical string) ([]models.Symbol, error) {
+	return s.filter(func(symbol models.Symbol) bool {
+		return symbol.Canonical == canonical
+	}), nil
+}
+
+func (s *MemoryStore) Count(ctx context.Context) (int64, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return int64(len(s.symbols)), nil
+}
+
+func (s *MemoryStore) CountByExchange(ctx context.Context, exchange string) (int64, error) {
+	symbols, _ := s.ListByExchange(ctx, exchange)
+	return int64(len(symbols)), nil
+}
+
+// CreateBatch inserts all symbols or none, failing on a duplicate combination
+// the same way the unique index does in the database.
+func (s *MemoryStore) CreateBatch(ctx context.Context, symbols []models.Symbol) error {
+	return s.CreateBatchWithNotifications(ctx, symbols, nil)
+}
+
+func (s *MemoryStore) CreateBatchWithNotifications(ctx context.Context, symbols []models.Symbol, notifications []models.Notification) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.checkNew(symbols); err != nil {
+		return err
+	}
+	s.insert(symbols)
+	s.appendNotifications(notifications)
+
+	return nil
+}
+
+// ApplyChanges validates the new symbols before touching anything, so a
+// failed call changes nothing, like the transaction of GormStore.
+func (s *MemoryStore) ApplyChanges(ctx context.Context, changes *models.SymbolChanges, delistedAt time.Time, notifications []models.Notification) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.checkNew(changes.New); err != nil {
+		return err
+	}
+
+	for _, symbol := range changes.Delisted {
+		s.markDelisted(symbol.Key(), delistedAt)
+	}
+	for _, symbol := range changes.Expired {
+		s.markDelisted(symbol.Key(), delistedAt)
+	}
+	for _, symbol := range changes.Relisted {
+		s.markRelisted(symbol.Key())
+	}
+	s.updateMetadata(changes.Updated)
+	s.updateGuardStates(changes.GuardStates)
+	s.insert(changes.New)
+	s.appendNotifications(notifications)
+
+	return nil
+}
+
+// checkNew fails on a duplicate combination the same way the unique index
+// does in the database.
+func (s *MemoryStore) checkNew(symbols []models.Symbol) error {
+	seen := make(map[string]bool, len(symbols))
+	for _, symbol := range symbols {
+		combination := symbol.Key()
+		if _, exists := s.symbols[combination]; exists || seen[combination] {
+			return fmt.Errorf("duplicate combination %s", combination)
+		}
+		seen[combination] = true
+	}
+	return nil
+}
+
+func (s *MemoryStore) insert(symbols []models.Symbol) {
+	for _, symbol := range symbols {
+		symbol.ID = s.nextID
+		symbol.Combination = symbol.Key()
+		if symbol.Status == "" {
+			symbol.Status = models.StatusActive
+		}
+		if symbol.CreatedAt.IsZero() {
+			symbol.CreatedAt = time.Now()
+		}
+		s.symbols[symbol.Combination] = symbol
+		s.nextID++
+	}
+}
+
+func (s *MemoryStore) MarkDelisted(ctx context.Context, combinations []string, at time.Time) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, combination := range combinations {
+		s.markDelisted(combination, at)
+	}
+
+	return nil
+}
+
+func (s *MemoryStore) markDelisted(combination string, at time.Time) {
+	symbol, ok := s.symbols[combination]
+	if !ok {
+		return
+	}
+	symbol.Status = models.StatusDelisted
+	symbol.DelistedAt = &at
+	s.symbols[combination] = symbol
+}
+
+func (s *MemoryStore) MarkRelisted(ctx context.Context, combinations []string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, combination := range combinations {
+		s.markRelisted(combination)
+	}
+
+	return nil
+}
+
+func (s *MemoryStore) markRelisted(combination string) {
+	symbol, ok := s.symbols[combination]
+	if !ok {
+		return
+	}
+	symbol.Status = models.StatusActive
+	symbol.DelistedAt = nil
+	s.symbols[combination] = symbol
+}
+
+func (s *MemoryStore) UpdateMetadata(ctx context.Context, symbols []models.Symbol) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.updateMetadata(symbols)
+
+	return nil
+}
+
+func (s *MemoryStore) updateMetadata(symbols []models.Symbol) {
+	for _, updated := range symbols {
+		symbol, ok := s.symbols[updated.Key()]
+		if !ok {
+			continue
+		}
+		symbol.InstrumentInfo = updated.InstrumentInfo
+		symbol.DeliveryAt = updated.DeliveryAt
+		s.symbols[symbol.Combination] = symbol
+	}
+}
+
+func (s *MemoryStore) UpdateListedAt(ctx context.Context, symbols []models.Symbol) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	updated := 0
+	for _, fetched := range symbols {
+		if fetched.ListedAt == nil {
+			continue
+		}
+		symbol, ok := s.symbols[fetched.Key()]
+		if !ok || (symbol.ListedAt != nil && symbol.ListedAt.Equal(*fetched.ListedAt)) {
+			continue
+		}
+		listedAt := *fetched.ListedAt
+		symbol.ListedAt = &listedAt
+		s.symbols[symbol.Combination] = symbol
+		updated++
+	}
+
+	return updated, nil
+}
+
+func (s *MemoryStore) SaveFetchResults(ctx context.Context, results []models.MarketFetchResult) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, result := range results {
+		key := result.Exchange + "-" + string(result.Market)
+		// like the GORM upsert, a new fetch keeps the guard's hold
+		result.HeldPolls = s.fetchResults[key].HeldPolls
+		result.HeldSince = s.fetchResults[key].HeldSince
+		s.fetchResults[key] = result
+	}
+
+	return nil
+}
+
+func (s *MemoryStore) ListFetchResults(ctx context.Context) ([]models.MarketFetchResult, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	results := make([]models.MarketFetchResult, 0, len(s.fetchResults))
+	for _, result := range s.fetchResults {
+		results = append(results, result)
+	}
+
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].Exchange != results[j].Exchange {
+			return results[i].Exchange < results[j].Exchange
+		}
+		return results[i].Market < results[j].Market
+	})
+	return results, nil
+}
+
+func (s *MemoryStore) updateGuardStates(states []models.GuardState) {
+	for _, state := range states {
+		key := state.Exchange + "-" + string(state.Market)
+		if result, ok := s.fetchResults[key]; ok {
+			result.HeldPolls = state.HeldPolls
+			result.HeldSince = state.HeldSince
+			s.fetchResults[key] = result
+		}
+	}
+}
+
+func (s *MemoryStore) EnqueueNotifications(ctx context.Context, notifications []models.Notification) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.appendNotifications(notifications)
+	return nil
+}
+
+// appendNotifications assigns increasing IDs; the caller holds s.mu.
+func (s *MemoryStore) appendNotifications(notifications []models.Notification) {
+	for _, notification := range notifications {
+		notification.ID = s.nextNotificationID
+		s.nextNotificationID++
+		if notification.Status == "" {
+			notification.Status = models.NotificationPending
+		}
+		s.notifications = append(s.notifications, notification)
+	}
+}
+
+func (s *MemoryStore) ListPendingNotifications(ctx context.Context, channel string, limit int) ([]models.Notification, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var pending []models.Notification
+	for _, notification := range s.notifications {
+		if len(pending) >= limit {
+			break
+		}
+		if notification.Channel == channel && notification.Status == models.NotificationPending {
+			pending = append(pending, notification)
+		}
+	}
+	return pending, nil
+}
+
+func (s *MemoryStore) CountPendingNotifications(ctx context.Context, now time.Time) (int64, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var count int64
+	for _, notification := range s.notifications {
+		if notification.Status != models.NotificationPending {
+			continue
+		}
+		if notification.Attempts > 0 || !notification.NextAttemptAt.After(now) {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (s *MemoryStore) UpdateNotification(ctx context.Context, notification models.Notification) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	index := sort.Search(len(s.notifications), func(i int) bool {
+		return s.notifications[i].ID >= notification.ID
+	})
+	if index == len(s.notifications) || s.notifications[index].ID != notification.ID {
+		return fmt.Errorf("notification %d not found", notification.ID)
+	}
+	stored := &s.notifications[index]
+	stored.Status = notification.Status
+	stored.Attempts = notification.Attempts
+	stored.NextAttemptAt = notification.NextAttemptAt
+	stored.LastError = notification.LastError
+	stored.SentAt = notification.SentAt
+	return nil
+}
+
+func (s *MemoryStore) DeleteNotifications(ctx context.Context, before time.Time) (int64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	kept := s.notifications[:0]
+	var deleted int64
+	for _, notification := range s.notifications {
+		if notification.Status != models.NotificationPending && notification.CreatedAt.Before(before) {
+			deleted++
+			continue
+		}
+		kept = append(kept, notification)
+	}
+	s.notifications = kept
+	return deleted, nil
+}
+
+func (s *MemoryStore) filter(match func(models.Symbol) bool) []models.Symbol {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var symbols []models.Symbol
+	for _, symbol := range s.symbols {
+		if match(symbol) {
+			symbols = append(symbols, symbol)
+		}
+	}
+
+	sort.Slice(symbols, func(i, j int) bool { return symbols[i].ID < symbols[j].ID })
+	return symbols
+}
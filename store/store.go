@@ -0,0 +1,59 @@
+package store
+
+import (
+	"all_exchange_symbol/models"
+	"context"
+	"errors"
+	"time"
+)
+
+var ErrNotFound = errors.New("symbol not found")
+
+// SymbolStore abstracts persistence of symbols so that the processor and
+// writer can run against MySQL/SQLite (GormStore) or memory (MemoryStore).
+// Every call takes the sync cycle's context so a deadline or shutdown signal
+// also aborts in-flight queries.
+type SymbolStore interface {
+	FindByCombination(ctx context.Context, combination string) (*models.Symbol, error)
+	ListAll(ctx context.Context) ([]models.Symbol, error)
+	ListByExchange(ctx context.Context, exchange string) ([]models.Symbol, error)
+	ListByType(ctx context.Context, symbolType models.MarketType) ([]models.Symbol, error)
+	ListByExchangeAndType(ctx context.Context, exchange string, symbolType models.MarketType) ([]models.Symbol, error)
+	ListByCanonical(ctx context.Context, canonical string) ([]models.Symbol, error)
+	Count(ctx context.Context) (int64, error)
+	CountByExchange(ctx context.Context, exchange string) (int64, error)
+	CreateBatch(ctx context.Context, symbols []models.Symbol) error
+	// CreateBatchWithNotifications inserts symbols and the outbox messages
+	// announcing them atomically, so a stored symbol is never left unannounced.
+	CreateBatchWithNotifications(ctx context.Context, symbols []models.Symbol, notifications []models.Notification) error
+	// ApplyChanges writes one poll's changes and the outbox messages that
+	// announce them atomically: new symbols, delistings and expiries at
+	// delistedAt, relistings, metadata and the delist guard's hold, which
+	// SaveFetchResults leaves untouched. A crash can therefore never store a
+	// change without its alert, which would be lost because the change is
+	// not detected again.
+	ApplyChanges(ctx context.Context, changes *models.SymbolChanges, delistedAt time.Time, notifications []models.Notification) error
+	MarkDelisted(ctx context.Context, combinations []string, at time.Time) error
+	MarkRelisted(ctx context.Context, combinations []string) error
+	UpdateMetadata(ctx context.Context, symbols []models.Symbol) error
+	// UpdateListedAt stores the exchange listing time of existing symbols
+	// that report one and returns how many rows changed.
+	UpdateListedAt(ctx context.Context, symbols []models.Symbol) (int, error)
+	SaveFetchResults(ctx context.Context, results []models.MarketFetchResult) error
+	ListFetchResults(ctx context.Context) ([]models.MarketFetchResult, error)
+	EnqueueNotifications(ctx context.Context, notifications []models.Notification) error
+	// ListPendingNotifications returns up to limit pending notifications of
+	// one channel in delivery (ID) order.
+	ListPendingNotifications(ctx context.Context, channel string, limit int) ([]models.Notification, error)
+	// CountPendingNotifications counts pending notifications that are due at
+	// now or already failed an attempt. Rows a notifier scheduled for later,
+	// such as daily digest mails, are waiting as intended and not counted.
+	CountPendingNotifications(ctx context.Context, now time.Time) (int64, error)
+	// UpdateNotification stores the delivery state of a notification:
+	// status, attempts, next attempt, last error and sent time.
+	UpdateNotification(ctx context.Context, notification models.Notification) error
+	// DeleteNotifications removes sent and failed notifications created
+	// before the given time and returns how many were removed. Pending rows
+	// are kept however old they are.
+	DeleteNotifications(ctx context.Context, before time.Time) (int64, error)
+}
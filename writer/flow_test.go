@@ -0,0 +1,249 @@
+package writer_test
+
+import (
+	"all_exchange_symbol/exchanges"
+	"all_exchange_symbol/models"
+	"all_exchange_symbol/notifier"
+	"all_exchange_symbol/processor"
+	"all_exchange_symbol/reader"
+	"all_exchange_symbol/store"
+	"all_exchange_symbol/writer"
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeExchange serves a fixed symbol list per market; a market mapped to an
+// error fails to fetch.
+type fakeExchange struct {
+	name    string
+	mu      sync.Mutex
+	symbols map[models.MarketType][]string
+	failing map[models.MarketType]error
+}
+
+func (f *fakeExchange) GetName() string {
+	return f.name
+}
+
+func (f *fakeExchange) SupportedMarkets() []models.MarketType {
+	return []models.MarketType{models.MarketSpot, models.MarketLinearPerpetual}
+}
+
+func (f *fakeExchange) FetchSymbols(ctx context.Context, market models.MarketType) ([]models.Symbol, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if err := f.failing[market]; err != nil {
+		return nil, err
+	}
+
+	var symbols []models.Symbol
+	for _, name := range f.symbols[market] {
+		symbols = append(symbols, models.Symbol{
+			Exchange:       f.name,
+			Type:           market,
+			Symbol:         name,
+			InstrumentInfo: models.InstrumentInfo{ExchangeStatus: "TRADING"},
+		})
+	}
+	return symbols, nil
+}
+
+func (f *fakeExchange) set(market models.MarketType, names []string, err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.symbols[market] = names
+	f.failing[market] = err
+}
+
+// recorder is a notifier that renders each alert as "kind: symbols" and
+// records what was sent.
+type recorder struct {
+	mu   sync.Mutex
+	sent []string
+}
+
+func (r *recorder) Name() string {
+	return "recorder"
+}
+
+func (r *recorder) Format(alert notifier.Alert) []string {
+	var names []string
+	for _, symbol := range alert.Symbols {
+		names = append(names, symbol.Symbol)
+	}
+	sort.Strings(names)
+	return []string{string(alert.Kind) + ": " + strings.Join(names, ",")}
+}
+
+func (r *recorder) Send(ctx context.Context, message string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.sent = append(r.sent, message)
+	return nil
+}
+
+// take returns the messages sent since the last call.
+func (r *recorder) take() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	messages := r.sent
+	r.sent = nil
+	return messages
+}
+
+func names(prefix string, n int) []string {
+	var result []string
+	for i := 0; i < n; i++ {
+		result = append(result, fmt.Sprintf("%s%02dUSDT", prefix, i))
+	}
+	return result
+}
+
+// pipeline runs one poll the way the daemon does: fetch, record the fetch
+// report, process and write.
+type pipeline struct {
+	reader    *reader.Reader
+	processor *processor.Processor
+	writer    *writer.Writer
+	store     store.SymbolStore
+}
+
+func (p *pipeline) poll(t *testing.T) *models.SymbolChanges {
+	t.Helper()
+	ctx := context.Background()
+
+	symbols, report, err := p.reader.FetchAllSymbols(ctx)
+	if err != nil {
+		t.Fatalf("FetchAllSymbols: %v", err)
+	}
+	if err := p.writer.WriteFetchReport(ctx, report); err != nil {
+		t.Fatalf("WriteFetchReport: %v", err)
+	}
+	changes, err := p.processor.ProcessSymbols(ctx, symbols, report)
+	if err != nil {
+		t.Fatalf("ProcessSymbols: %v", err)
+	}
+	if err := p.writer.ProcessAndWrite(ctx, changes); err != nil {
+		t.Fatalf("ProcessAndWrite: %v", err)
+	}
+	return changes
+}
+
+func (p *pipeline) status(t *testing.T, market models.MarketType, symbol string) string {
+	t.Helper()
+
+	stored, err := p.store.FindByCombination(context.Background(), models.BuildCombination("binance", market, symbol))
+	if err != nil {
+		t.Fatalf("FindByCombination(%s): %v", symbol, err)
+	}
+	return stored.Status
+}
+
+func expectSent(t *testing.T, got []string, want ...string) {
+	t.Helper()
+
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("sent:\n  %s\nwant:\n  %s", strings.Join(got, "\n  "), strings.Join(want, "\n  "))
+	}
+}
+
+func TestFetchProcessWriteFlow(t *testing.T) {
+	spot := names("S", 25)
+	perps := names("P", 25)
+	exchange := &fakeExchange{
+		name:    "binance",
+		symbols: map[models.MarketType][]string{models.MarketSpot: spot, models.MarketLinearPerpetual: perps},
+		failing: map[models.MarketType]error{},
+	}
+
+	symbolStore := store.NewMemoryStore()
+	sink := &recorder{}
+	p := &pipeline{
+		reader:    reader.NewReader([]exchanges.ExchangeInterface{exchange}),
+		processor: processor.NewProcessor(symbolStore),
+		writer:    writer.NewWriter(symbolStore, []notifier.Notifier{sink}),
+		store:     symbolStore,
+	}
+	p.processor.SetDelistGuard(processor.DelistGuard{DefaultThreshold: 0.2, MinStoredCount: 20, ConfirmAfter: 200 * time.Millisecond})
+
+	// first poll: everything is new
+	changes := p.poll(t)
+	if len(changes.New) != 50 {
+		t.Fatalf("first poll found %d new symbols, want 50", len(changes.New))
+	}
+	expectSent(t, sink.take(), "new_symbols: "+strings.Join(append(append([]string{}, perps...), spot...), ","))
+
+	// a listing and a delisting in the same poll
+	exchange.set(models.MarketSpot, append(append([]string{}, spot[1:]...), "NEWUSDT"), nil)
+	p.poll(t)
+	expectSent(t, sink.take(), "new_symbols: NEWUSDT", "delisted: S00USDT")
+	if status := p.status(t, models.MarketSpot, "S00USDT"); status != models.StatusDelisted {
+		t.Errorf("S00USDT status %q, want delisted", status)
+	}
+
+	// the delisted symbol comes back
+	exchange.set(models.MarketSpot, append(append([]string{}, spot...), "NEWUSDT"), nil)
+	p.poll(t)
+	expectSent(t, sink.take(), "relisted: S00USDT")
+	if status := p.status(t, models.MarketSpot, "S00USDT"); status != models.StatusActive {
+		t.Errorf("S00USDT status %q, want active", status)
+	}
+
+	// a failed market is never read as delisted
+	exchange.set(models.MarketSpot, nil, errors.New("connection reset"))
+	p.poll(t)
+	expectSent(t, sink.take())
+	if status := p.status(t, models.MarketSpot, "S01USDT"); status != models.StatusActive {
+		t.Errorf("S01USDT status %q after a failed fetch, want active", status)
+	}
+	exchange.set(models.MarketSpot, append(append([]string{}, spot...), "NEWUSDT"), nil)
+
+	// a sharp drop is held back and announced once
+	exchange.set(models.MarketLinearPerpetual, perps[:5], nil)
+	changes = p.poll(t)
+	if len(changes.Delisted) != 0 || len(changes.Warnings) != 1 {
+		t.Fatalf("drop: %d delisted, %d warnings; want 0 and 1", len(changes.Delisted), len(changes.Warnings))
+	}
+	expectSent(t, sink.take(), "guard_warning: ")
+
+	changes = p.poll(t)
+	if len(changes.Delisted) != 0 || len(changes.Warnings) != 1 {
+		t.Fatalf("second held poll: %d delisted, %d warnings; want 0 and 1", len(changes.Delisted), len(changes.Warnings))
+	}
+	expectSent(t, sink.take())
+	if status := p.status(t, models.MarketLinearPerpetual, "P10USDT"); status != models.StatusActive {
+		t.Errorf("P10USDT status %q while held, want active", status)
+	}
+
+	// once the drop persisted past ConfirmAfter it is applied
+	time.Sleep(250 * time.Millisecond)
+	changes = p.poll(t)
+	if len(changes.Delisted) != 20 || len(changes.Warnings) != 0 {
+		t.Fatalf("confirmed drop: %d delisted, %d warnings; want 20 and 0", len(changes.Delisted), len(changes.Warnings))
+	}
+	expectSent(t, sink.take(), "delisted: "+strings.Join(perps[5:], ","))
+	if status := p.status(t, models.MarketLinearPerpetual, "P10USDT"); status != models.StatusDelisted {
+		t.Errorf("P10USDT status %q after the confirmed drop, want delisted", status)
+	}
+
+	// nothing changed, nothing sent
+	changes = p.poll(t)
+	if changes.HasChanges() || len(changes.Warnings) != 0 {
+		t.Errorf("steady poll reported changes: %+v", changes)
+	}
+	expectSent(t, sink.take())
+
+	if pending, err := symbolStore.CountPendingNotifications(context.Background(), time.Now()); err != nil || pending != 0 {
+		t.Errorf("%d notifications left in the outbox (err %v)", pending, err)
+	}
+}
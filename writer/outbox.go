@@ -0,0 +1,249 @@
+package writer
+
+import (
+	"all_exchange_symbol/models"
+	"all_exchange_symbol/notifier"
+	"context"
+	"errors"
+	"log"
+	"time"
+)
+
+const (
+	// outboxBatchSize is how many pending notifications are loaded at once.
+	outboxBatchSize = 50
+
+	// mergeBatchSize is how many due notifications a notifier.Batcher may
+	// merge into one delivery, such as a daily digest mail.
+	mergeBatchSize = 1000
+
+	// retryBaseDelay and retryMaxDelay bound the exponential backoff of
+	// failed sends that the platform gave no retry delay for.
+	retryBaseDelay = 5 * time.Second
+	retryMaxDelay  = 10 * time.Minute
+
+	// maxInlineWait is the longest a delivery waits for a due retry before
+	// leaving the rest of a channel's outbox to the next call.
+	maxInlineWait = 30 * time.Second
+
+	// defaultRetention is how long sent and failed notifications stay in the
+	// outbox before they are pruned, and pruneInterval how often that runs.
+	defaultRetention = 7 * 24 * time.Hour
+	pruneInterval    = time.Hour
+)
+
+// FlushPending delivers the outbox of every notifier and returns how many
+// messages are still pending, leaving out those a notifier scheduled for
+// later such as digest mails. Each channel is delivered in order and stops
+// at the first message that has to wait longer than maxInlineWait, so later
+// messages never overtake it; one platform's outage does not hold back the
+// others. Sent and failed messages older than the retention are pruned at
+// most once per pruneInterval.
+func (w *Writer) FlushPending(ctx context.Context) int {
+	if len(w.notifiers) == 0 {
+		return 0
+	}
+
+	w.deliverMu.Lock()
+	defer w.deliverMu.Unlock()
+
+	for _, n := range w.notifiers {
+		sent := w.flushChannel(ctx, n)
+		if sent > 0 {
+			log.Printf("Delivered %d %s messages", sent, n.Name())
+		}
+	}
+
+	remaining, err := w.store.CountPendingNotifications(context.WithoutCancel(ctx), time.Now())
+	if err != nil {
+		log.Printf("Error counting notification outbox: %v", err)
+	}
+	if remaining > 0 {
+		log.Printf("%d notifications still pending in the outbox", remaining)
+	}
+
+	w.prune(context.WithoutCancel(ctx), time.Now())
+	return int(remaining)
+}
+
+// prune deletes delivered and failed notifications older than the
+// retention; the caller holds deliverMu.
+func (w *Writer) prune(ctx context.Context, now time.Time) {
+	if w.retention <= 0 || now.Sub(w.lastPrune) < pruneInterval {
+		return
+	}
+	w.lastPrune = now
+
+	deleted, err := w.store.DeleteNotifications(ctx, now.Add(-w.retention))
+	if err != nil {
+		log.Printf("Error pruning notification outbox: %v", err)
+		return
+	}
+	if deleted > 0 {
+		log.Printf("Pruned %d delivered notifications older than %v", deleted, w.retention)
+	}
+}
+
+func (w *Writer) flushChannel(ctx context.Context, n notifier.Notifier) int {
+	if batcher, ok := n.(notifier.Batcher); ok {
+		return w.flushBatch(ctx, n, batcher)
+	}
+
+	sent := 0
+	for ctx.Err() == nil {
+		pending, err := w.store.ListPendingNotifications(ctx, n.Name(), outboxBatchSize)
+		if err != nil {
+			log.Printf("Error loading %s outbox: %v", n.Name(), err)
+			break
+		}
+		if len(pending) == 0 {
+			break
+		}
+
+		delivered, more := w.deliver(ctx, n, pending)
+		sent += delivered
+		if !more {
+			break
+		}
+	}
+	return sent
+}
+
+// deliver sends pending notifications in order and reports how many were
+// sent and whether delivery should continue with the next batch.
+func (w *Writer) deliver(ctx context.Context, n notifier.Notifier, pending []models.Notification) (int, bool) {
+	sent := 0
+	for _, notification := range pending {
+		if wait := time.Until(notification.NextAttemptAt); wait > 0 {
+			if wait > maxInlineWait || !sleepContext(ctx, wait) {
+				return sent, false
+			}
+		}
+
+		err := n.Send(ctx, notification.Text)
+		if err != nil && ctx.Err() != nil {
+			// the send was cut short by shutdown, not rejected by the platform
+			return sent, false
+		}
+
+		if !w.record(ctx, n, &notification, err) {
+			return sent, false
+		}
+		if notification.Status == models.NotificationSent {
+			sent++
+		}
+
+		if notification.Status == models.NotificationPending {
+			// reload so the retry is waited for, or left for later, in order
+			return sent, time.Until(notification.NextAttemptAt) <= maxInlineWait
+		}
+	}
+
+	return sent, len(pending) == outboxBatchSize
+}
+
+// flushBatch hands all due notifications of a channel to one SendBatch call.
+// Only the leading due rows are taken, so a held back or retried row is
+// never overtaken.
+func (w *Writer) flushBatch(ctx context.Context, n notifier.Notifier, batcher notifier.Batcher) int {
+	pending, err := w.store.ListPendingNotifications(ctx, n.Name(), mergeBatchSize)
+	if err != nil {
+		log.Printf("Error loading %s outbox: %v", n.Name(), err)
+		return 0
+	}
+
+	now := time.Now()
+	due := 0
+	for due < len(pending) && !pending[due].NextAttemptAt.After(now) {
+		due++
+	}
+	if due == 0 {
+		return 0
+	}
+
+	messages := make([]string, due)
+	for i := range messages {
+		messages[i] = pending[i].Text
+	}
+
+	errs := batcher.SendBatch(ctx, messages)
+	sent := 0
+	for i := range messages {
+		if errs[i] != nil && ctx.Err() != nil {
+			// the send was cut short by shutdown, not rejected by the platform
+			continue
+		}
+		if !w.record(ctx, n, &pending[i], errs[i]) {
+			break
+		}
+		if pending[i].Status == models.NotificationSent {
+			sent++
+		}
+	}
+	return sent
+}
+
+// record stores the outcome of one send attempt: sent, failed for good, or
+// pending again with its next attempt time. It reports whether the outbox
+// row could be updated.
+func (w *Writer) record(ctx context.Context, n notifier.Notifier, notification *models.Notification, err error) bool {
+	notification.Attempts++
+	now := time.Now()
+	switch {
+	case err == nil:
+		notification.Status = models.NotificationSent
+		notification.SentAt = &now
+		notification.LastError = ""
+	case isPermanent(err):
+		log.Printf("%s rejected message %d, giving up: %v", n.Name(), notification.ID, err)
+		notification.Status = models.NotificationFailed
+		notification.LastError = err.Error()
+	default:
+		log.Printf("Error sending %s message %d (attempt %d): %v", n.Name(), notification.ID, notification.Attempts, err)
+		notification.NextAttemptAt = now.Add(retryDelay(notification.Attempts, err))
+		notification.LastError = err.Error()
+	}
+
+	// a message the platform accepted must be marked even if ctx just expired
+	if updateErr := w.store.UpdateNotification(context.WithoutCancel(ctx), *notification); updateErr != nil {
+		log.Printf("Error updating %s outbox message %d: %v", n.Name(), notification.ID, updateErr)
+		return false
+	}
+	return true
+}
+
+// retryDelay honors the wait the platform asked for (Telegram's retry_after,
+// Retry-After headers) and otherwise backs off exponentially from
+// retryBaseDelay up to retryMaxDelay.
+func retryDelay(attempts int, err error) time.Duration {
+	var apiErr *notifier.Error
+	if errors.As(err, &apiErr) && apiErr.RetryAfter > 0 {
+		return apiErr.RetryAfter
+	}
+
+	delay := retryBaseDelay
+	for i := 1; i < attempts && delay < retryMaxDelay; i++ {
+		delay *= 2
+	}
+	if delay > retryMaxDelay {
+		delay = retryMaxDelay
+	}
+	return delay
+}
+
+func isPermanent(err error) bool {
+	var apiErr *notifier.Error
+	return errors.As(err, &apiErr) && !apiErr.Temporary()
+}
+
+func sleepContext(ctx context.Context, d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
@@ -0,0 +1,191 @@
+package writer
+
+import (
+	"all_exchange_symbol/models"
+	"all_exchange_symbol/notifier"
+	"all_exchange_symbol/store"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+// digestNotifier holds every message back for a day, like a daily digest.
+type digestNotifier struct {
+	sent int
+}
+
+func (d *digestNotifier) Name() string {
+	return "digest"
+}
+
+func (d *digestNotifier) Format(alert notifier.Alert) []string {
+	return []string{alert.Title}
+}
+
+func (d *digestNotifier) Send(ctx context.Context, message string) error {
+	d.sent++
+	return nil
+}
+
+func (d *digestNotifier) NextSend(queuedAt time.Time) time.Time {
+	return queuedAt.Add(24 * time.Hour)
+}
+
+func TestScheduledNotificationsAreNotReportedAsPending(t *testing.T) {
+	ctx := context.Background()
+	symbolStore := store.NewMemoryStore()
+	digest := &digestNotifier{}
+	w := NewWriter(symbolStore, []notifier.Notifier{digest})
+
+	if err := w.SendSummary(ctx, 10, &models.SymbolChanges{}, nil); err != nil {
+		t.Errorf("SendSummary with a scheduled digest: %v", err)
+	}
+	if remaining := w.FlushPending(ctx); remaining != 0 {
+		t.Errorf("FlushPending reported %d pending, want 0", remaining)
+	}
+	if digest.sent != 0 {
+		t.Errorf("digest sent %d messages before its time", digest.sent)
+	}
+
+	scheduled, err := symbolStore.ListPendingNotifications(ctx, "digest", 10)
+	if err != nil {
+		t.Fatalf("ListPendingNotifications: %v", err)
+	}
+	if len(scheduled) != 1 {
+		t.Fatalf("%d scheduled messages in the outbox, want 1", len(scheduled))
+	}
+
+	// a failed attempt is reported even while its retry is not due
+	scheduled[0].Attempts = 1
+	if err := symbolStore.UpdateNotification(ctx, scheduled[0]); err != nil {
+		t.Fatalf("UpdateNotification: %v", err)
+	}
+	if remaining := w.FlushPending(ctx); remaining != 1 {
+		t.Errorf("FlushPending reported %d pending after a failed attempt, want 1", remaining)
+	}
+}
+
+// plainNotifier sends every message at once and keeps what it sent.
+type plainNotifier struct {
+	sent []string
+}
+
+func (p *plainNotifier) Name() string {
+	return "plain"
+}
+
+func (p *plainNotifier) Format(alert notifier.Alert) []string {
+	return []string{alert.Title}
+}
+
+func (p *plainNotifier) Send(ctx context.Context, message string) error {
+	p.sent = append(p.sent, message)
+	return nil
+}
+
+// pruneCounter records the outbox prunes of the wrapped store.
+type pruneCounter struct {
+	store.SymbolStore
+	calls   int
+	deleted int64
+}
+
+func (p *pruneCounter) DeleteNotifications(ctx context.Context, before time.Time) (int64, error) {
+	deleted, err := p.SymbolStore.DeleteNotifications(ctx, before)
+	p.calls++
+	p.deleted += deleted
+	return deleted, err
+}
+
+func TestFlushPendingPrunesDeliveredNotifications(t *testing.T) {
+	ctx := context.Background()
+	symbolStore := &pruneCounter{SymbolStore: store.NewMemoryStore()}
+	plain := &plainNotifier{}
+	w := NewWriter(symbolStore, []notifier.Notifier{plain})
+
+	old := time.Now().Add(-defaultRetention - time.Hour)
+	notifications := append(models.NewNotifications("plain", []string{"a", "b"}),
+		models.NewNotifications("removed", []string{"undelivered"})...)
+	for i := range notifications {
+		notifications[i].CreatedAt = old
+	}
+	if err := symbolStore.EnqueueNotifications(ctx, notifications); err != nil {
+		t.Fatalf("EnqueueNotifications: %v", err)
+	}
+
+	w.FlushPending(ctx)
+	if strings.Join(plain.sent, ",") != "a,b" {
+		t.Errorf("sent %v, want [a b]", plain.sent)
+	}
+	if symbolStore.deleted != 2 {
+		t.Errorf("pruned %d notifications, want the 2 sent ones", symbolStore.deleted)
+	}
+	undelivered, err := symbolStore.ListPendingNotifications(ctx, "removed", 10)
+	if err != nil {
+		t.Fatalf("ListPendingNotifications: %v", err)
+	}
+	if len(undelivered) != 1 {
+		t.Errorf("%d undelivered notifications left, want 1 (pending rows are never pruned)", len(undelivered))
+	}
+
+	// rows queued after a prune are still delivered and marked sent
+	if err := symbolStore.EnqueueNotifications(ctx, models.NewNotifications("plain", []string{"c"})); err != nil {
+		t.Fatalf("EnqueueNotifications: %v", err)
+	}
+	w.FlushPending(ctx)
+	if strings.Join(plain.sent, ",") != "a,b,c" {
+		t.Errorf("sent %v, want [a b c]", plain.sent)
+	}
+	if pending, err := symbolStore.ListPendingNotifications(ctx, "plain", 10); err != nil || len(pending) != 0 {
+		t.Errorf("plain outbox after delivery: %d pending, %v", len(pending), err)
+	}
+	if symbolStore.calls != 1 {
+		t.Errorf("outbox pruned %d times, want once per pruneInterval", symbolStore.calls)
+	}
+}
+
+func TestIsPermanent(t *testing.T) {
+	tests := []struct {
+		err       error
+		permanent bool
+	}{
+		{&notifier.Error{StatusCode: http.StatusBadRequest}, true},
+		{&notifier.Error{StatusCode: http.StatusForbidden}, true},
+		{&notifier.Error{StatusCode: http.StatusNotFound}, true},
+		{&notifier.Error{StatusCode: http.StatusTooManyRequests}, false},
+		{&notifier.Error{StatusCode: http.StatusInternalServerError}, false},
+		{&notifier.Error{StatusCode: http.StatusBadGateway}, false},
+		{fmt.Errorf("wrapped: %w", &notifier.Error{StatusCode: http.StatusUnauthorized}), true},
+		{errors.New("connection reset by peer"), false},
+	}
+
+	for _, tt := range tests {
+		if got := isPermanent(tt.err); got != tt.permanent {
+			t.Errorf("isPermanent(%v) = %v, want %v", tt.err, got, tt.permanent)
+		}
+	}
+}
+
+func TestRetryDelay(t *testing.T) {
+	tests := []struct {
+		attempts int
+		err      error
+		want     time.Duration
+	}{
+		{1, &notifier.Error{StatusCode: http.StatusTooManyRequests, RetryAfter: 42 * time.Second}, 42 * time.Second},
+		{1, errors.New("timeout"), retryBaseDelay},
+		{2, errors.New("timeout"), 2 * retryBaseDelay},
+		{4, &notifier.Error{StatusCode: http.StatusBadGateway}, 8 * retryBaseDelay},
+		{50, errors.New("timeout"), retryMaxDelay},
+	}
+
+	for _, tt := range tests {
+		if got := retryDelay(tt.attempts, tt.err); got != tt.want {
+			t.Errorf("retryDelay(%d, %v) = %v, want %v", tt.attempts, tt.err, got, tt.want)
+		}
+	}
+}
@@ -1,190 +1,276 @@
 package writer
 
 import (
-	"all_exchange_symbol/database"
 	"all_exchange_symbol/models"
-	"bytes"
-	"encoding/json"
+	"all_exchange_symbol/notifier"
+	"all_exchange_symbol/store"
+	"context"
 	"fmt"
 	"log"
-	"net/http"
+	"sync"
+	"time"
 )
 
+// maxErrorLength keeps a single failed-market line well inside one message.
+const maxErrorLength = 300
+
 type Writer struct {
-	telegramBotToken string
-	telegramChatID   string
-}
+	store     store.SymbolStore
+	notifiers []notifier.Notifier
+
+	// deliverMu keeps outbox deliveries from overlapping, which would send a
+	// message twice or out of order. It also guards lastPrune.
+	deliverMu sync.Mutex
 
-type TelegramMessage struct {
-	ChatID    string `json:"chat_id"`
-	Text      string `json:"text"`
-	ParseMode string `json:"parse_mode"`
+	retention time.Duration
+	lastPrune time.Time
 }
 
-func NewWriter(botToken, chatID string) *Writer {
+func NewWriter(symbolStore store.SymbolStore, notifiers []notifier.Notifier) *Writer {
 	return &Writer{
-		telegramBotToken: botToken,
-		telegramChatID:   chatID,
+		store:     symbolStore,
+		notifiers: notifiers,
+		retention: defaultRetention,
 	}
 }
 
-func (w *Writer) WriteSymbolsToDatabase(symbols []models.Symbol) error {
-	if len(symbols) == 0 {
-		log.Println("No new symbols to write to database")
-		return nil
-	}
+// SetNotificationRetention sets how long sent and failed notifications are
+// kept in the outbox; 0 keeps them forever.
+func (w *Writer) SetNotificationRetention(retention time.Duration) {
+	w.retention = retention
+}
 
-	result := database.DB.Create(&symbols)
-	if result.Error != nil {
-		log.Printf("Error writing symbols to database: %v", result.Error)
-		return result.Error
+// WriteChanges stores the changes of one poll together with the outbox
+// messages that announce them, in one transaction.
+func (w *Writer) WriteChanges(ctx context.Context, changes *models.SymbolChanges, notifications []models.Notification) error {
+	if err := w.store.ApplyChanges(ctx, changes, time.Now(), notifications); err != nil {
+		log.Printf("Error writing changes to database: %v", err)
+		return err
 	}
 
-	log.Printf("Successfully wrote %d symbols to database", len(symbols))
+	if len(changes.New) > 0 {
+		log.Printf("Successfully wrote %d symbols to database", len(changes.New))
+	} else {
+		log.Println("No new symbols to write to database")
+	}
+	if len(changes.Delisted) > 0 {
+		log.Printf("Marked %d symbols as delisted", len(changes.Delisted))
+	}
+	if len(changes.Expired) > 0 {
+		log.Printf("Marked %d expired contracts as delisted", len(changes.Expired))
+	}
+	if len(changes.Relisted) > 0 {
+		log.Printf("Marked %d symbols as relisted", len(changes.Relisted))
+	}
+	if len(changes.Updated) > 0 {
+		log.Printf("Updated metadata of %d symbols", len(changes.Updated))
+	}
+	if len(notifications) > 0 {
+		log.Printf("Queued %d notifications", len(notifications))
+	}
 	return nil
 }
 
-func (w *Writer) SendToTelegram(symbols []models.Symbol) error {
+// notifications renders alerts for every notifier into outbox rows. Nothing
+// is queued without notifiers; delivered rows are pruned by FlushPending
+// once they are older than the retention.
+func (w *Writer) notifications(alerts []notifier.Alert) []models.Notification {
+	var notifications []models.Notification
+	for _, n := range w.notifiers {
+		var rows []models.Notification
+		for _, alert := range alerts {
+			rows = append(rows, models.NewNotifications(n.Name(), n.Format(alert))...)
+		}
+		if scheduler, ok := n.(notifier.Scheduler); ok {
+			for i := range rows {
+				rows[i].NextAttemptAt = scheduler.NextSend(rows[i].CreatedAt)
+			}
+		}
+		notifications = append(notifications, rows...)
+	}
+	return notifications
+}
+
+func newSymbolsAlert(symbols []models.Symbol) []notifier.Alert {
 	if len(symbols) == 0 {
-		log.Println("No new symbols to send to Telegram")
 		return nil
 	}
 
-	message := w.formatTelegramMessage(symbols)
+	return []notifier.Alert{{
+		Kind:    notifier.AlertNewSymbols,
+		Title:   fmt.Sprintf("🚀 Found %d new trading symbols:", len(symbols)),
+		Symbols: symbols,
+	}}
+}
 
-	telegramMsg := TelegramMessage{
-		ChatID:    w.telegramChatID,
-		Text:      message,
-		ParseMode: "Markdown",
+func delistedAlert(symbols []models.Symbol) []notifier.Alert {
+	if len(symbols) == 0 {
+		return nil
 	}
 
-	jsonData, err := json.Marshal(telegramMsg)
-	if err != nil {
-		log.Printf("Error marshaling telegram message: %v", err)
-		return err
-	}
+	return []notifier.Alert{{
+		Kind:    notifier.AlertDelisted,
+		Title:   fmt.Sprintf("⚠️ %d trading symbols delisted:", len(symbols)),
+		Symbols: symbols,
+	}}
+}
 
-	url := fmt.Sprintf("https://api.telegram.org/bot%s/sendMessage", w.telegramBotToken)
+// statusEventTitles are the alert titles of each status event, with the
+// symbol count as the only argument.
+var statusEventTitles = map[models.StatusEvent]string{
+	models.EventPreListed: "🕒 %d trading symbols listed, trading not open yet:",
+	models.EventWentLive:  "🟢 %d trading symbols opened for trading:",
+	models.EventHalted:    "⏸ %d trading symbols halted:",
+	models.EventResumed:   "▶️ %d trading symbols resumed trading:",
+	models.EventDelisting: "⏳ %d trading symbols scheduled for delisting:",
+}
 
-	resp, err := http.Post(url, "application/json", bytes.NewBuffer(jsonData))
-	if err != nil {
-		log.Printf("Error sending telegram message: %v", err)
-		return err
-	}
-	defer resp.Body.Close()
+// transitionAlerts builds one alert per status event.
+func transitionAlerts(changes *models.SymbolChanges) []notifier.Alert {
+	var alerts []notifier.Alert
+	for _, event := range models.AllStatusEvents {
+		symbols := changes.TransitionsByEvent(event)
+		if len(symbols) == 0 {
+			continue
+		}
 
-	if resp.StatusCode != http.StatusOK {
-		log.Printf("Telegram API returned status code: %d", resp.StatusCode)
-		return fmt.Errorf("telegram API error: status code %d", resp.StatusCode)
+		alerts = append(alerts, notifier.Alert{
+			Kind:    notifier.AlertKind(event),
+			Title:   fmt.Sprintf(statusEventTitles[event], len(symbols)),
+			Symbols: symbols,
+		})
 	}
-
-	log.Printf("Successfully sent message to Telegram with %d new symbols", len(symbols))
-	return nil
+	return alerts
 }
 
-func (w *Writer) formatTelegramMessage(symbols []models.Symbol) string {
+func relistedAlert(symbols []models.Symbol) []notifier.Alert {
 	if len(symbols) == 0 {
-		return "No new symbols found."
+		return nil
 	}
 
-	message := fmt.Sprintf("🚀 *Found %d new trading symbols:*\n\n", len(symbols))
+	return []notifier.Alert{{
+		Kind:    notifier.AlertRelisted,
+		Title:   fmt.Sprintf("🔁 %d trading symbols relisted:", len(symbols)),
+		Symbols: symbols,
+	}}
+}
 
-	exchangeGroups := make(map[string][]models.Symbol)
-	for _, symbol := range symbols {
-		exchangeGroups[symbol.Exchange] = append(exchangeGroups[symbol.Exchange], symbol)
+// guardWarningAlert announces markets that were just held back; markets
+// still held from an earlier poll were announced then.
+func guardWarningAlert(warnings []models.GuardWarning) []notifier.Alert {
+	var lines []string
+	for _, warning := range warnings {
+		if !warning.FirstPoll() {
+			continue
+		}
+		lines = append(lines, fmt.Sprintf("📊 %s %s: %d → %d (-%.1f%%, threshold %.1f%%)",
+			warning.Exchange, warning.Market.Label(), warning.StoredCount, warning.FetchedCount,
+			warning.DropRatio*100, warning.Threshold*100))
+	}
+	if len(lines) == 0 {
+		return nil
 	}
+	lines = append(lines, "", "Previous snapshot kept. Check the exchange API before trusting this poll. "+
+		"The delistings are applied if the drop persists.")
+
+	return []notifier.Alert{{
+		Kind:  notifier.AlertGuard,
+		Title: "🛑 Delisting held back: symbol count dropped sharply",
+		Lines: lines,
+	}}
+}
 
-	for exchange, exchangeSymbols := range exchangeGroups {
-		spotCount := 0
-		futuresCount := 0
+// ProcessAndWrite stores the changes and queues their alerts in the outbox
+// of every notifier in the same transaction, then delivers it. A failed
+// delivery is retried by later calls and runs.
+func (w *Writer) ProcessAndWrite(ctx context.Context, changes *models.SymbolChanges) error {
+	// older alerts go out first so every channel stays in order
+	w.FlushPending(ctx)
 
-		for _, symbol := range exchangeSymbols {
-			if symbol.Type == "spot" {
-				spotCount++
-			} else {
-				futuresCount++
-			}
-		}
+	if len(w.notifiers) == 0 {
+		log.Println("No notifiers configured, skipping notification")
+	}
 
-		message += fmt.Sprintf("📊 *%s*:\n", exchange)
-		if spotCount > 0 {
-			message += fmt.Sprintf("   • Spot: %d symbols\n", spotCount)
-		}
-		if futuresCount > 0 {
-			message += fmt.Sprintf("   • Futures: %d symbols\n", futuresCount)
-		}
+	var alerts []notifier.Alert
+	alerts = append(alerts, newSymbolsAlert(tradableNow(changes.New))...)
+	alerts = append(alerts, delistedAlert(changes.Delisted)...)
+	alerts = append(alerts, transitionAlerts(changes)...)
+	alerts = append(alerts, relistedAlert(changes.Relisted)...)
+	alerts = append(alerts, guardWarningAlert(changes.Warnings)...)
+	if err := w.WriteChanges(ctx, changes, w.notifications(alerts)); err != nil {
+		return fmt.Errorf("failed to write to database: %v", err)
+	}
 
-		if len(exchangeSymbols) <= 10 {
-			for _, symbol := range exchangeSymbols {
-				message += fmt.Sprintf("   - `%s`\n", symbol.Symbol)
-			}
-		} else {
-			for i, symbol := range exchangeSymbols[:5] {
-				message += fmt.Sprintf("   - `%s`\n", symbol.Symbol)
-				if i == 4 {
-					message += fmt.Sprintf("   ... and %d more\n", len(exchangeSymbols)-5)
-				}
-			}
+	w.FlushPending(ctx)
+	return nil
+}
+
+// tradableNow drops new symbols that are not trading yet; they are announced
+// by the pre-listed status alert instead, and again when they open.
+func tradableNow(symbols []models.Symbol) []models.Symbol {
+	var tradable []models.Symbol
+	for _, symbol := range symbols {
+		if symbol.TradingStatus != models.TradingPreListing {
+			tradable = append(tradable, symbol)
 		}
-		message += "\n"
 	}
-
-	return message
+	return tradable
 }
 
-func (w *Writer) ProcessAndWrite(symbols []models.Symbol) error {
-	if err := w.WriteSymbolsToDatabase(symbols); err != nil {
-		return fmt.Errorf("failed to write to database: %v", err)
+// WriteFetchReport persists the latest per-market fetch status.
+func (w *Writer) WriteFetchReport(ctx context.Context, report *models.FetchReport) error {
+	if report == nil {
+		return nil
 	}
 
-	if w.telegramBotToken != "" && w.telegramChatID != "" {
-		if err := w.SendToTelegram(symbols); err != nil {
-			log.Printf("Failed to send to Telegram (continuing anyway): %v", err)
-		}
-	} else {
-		log.Println("Telegram credentials not provided, skipping notification")
+	if err := w.store.SaveFetchResults(ctx, report.Results); err != nil {
+		log.Printf("Error writing fetch report to database: %v", err)
+		return err
 	}
 
 	return nil
 }
 
-func (w *Writer) SendSummaryToTelegram(totalSymbols int, newSymbols int) error {
-	if w.telegramBotToken == "" || w.telegramChatID == "" {
-		log.Println("Telegram credentials not provided, skipping summary")
+func (w *Writer) SendSummary(ctx context.Context, totalSymbols int, changes *models.SymbolChanges, report *models.FetchReport) error {
+	if len(w.notifiers) == 0 {
+		log.Println("No notifiers configured, skipping summary")
 		return nil
 	}
 
-	message := "📈 *Symbol Sync Summary*\n\n"
-	message += fmt.Sprintf("🔍 Total symbols checked: %d\n", totalSymbols)
-	message += fmt.Sprintf("✨ New symbols found: %d\n", newSymbols)
-
-	if newSymbols == 0 {
-		message += "\n✅ No new symbols detected. All markets are up to date!"
+	lines := []string{
+		fmt.Sprintf("🔍 Total symbols checked: %d", totalSymbols),
+		fmt.Sprintf("✨ New symbols found: %d", len(changes.New)),
+		fmt.Sprintf("⚠️ Delisted symbols: %d", len(changes.Delisted)),
+		fmt.Sprintf("🔁 Relisted symbols: %d", len(changes.Relisted)),
+		fmt.Sprintf("🔄 Status changes: %d", len(changes.Transitions)),
 	}
 
-	telegramMsg := TelegramMessage{
-		ChatID:    w.telegramChatID,
-		Text:      message,
-		ParseMode: "Markdown",
+	if report != nil {
+		failed := report.Failed()
+		lines = append(lines, fmt.Sprintf("📡 Markets fetched: %d/%d", len(report.Results)-len(failed), len(report.Results)))
+		if len(failed) > 0 {
+			lines = append(lines, "", "❌ Failed markets:")
+			for _, result := range failed {
+				lines = append(lines, fmt.Sprintf("   - %s %s: %s", result.Exchange, result.Market.Label(),
+					notifier.Truncate(result.ErrorMessage, maxErrorLength)))
+			}
+		}
 	}
 
-	jsonData, err := json.Marshal(telegramMsg)
-	if err != nil {
-		return err
+	if !changes.HasChanges() {
+		lines = append(lines, "", "✅ No listing changes detected. All markets are up to date!")
 	}
 
-	url := fmt.Sprintf("https://api.telegram.org/bot%s/sendMessage", w.telegramBotToken)
-
-	resp, err := http.Post(url, "application/json", bytes.NewBuffer(jsonData))
-	if err != nil {
+	summary := notifier.Alert{Kind: notifier.AlertSummary, Title: "📈 Symbol Sync Summary", Lines: lines}
+	if err := w.store.EnqueueNotifications(ctx, w.notifications([]notifier.Alert{summary})); err != nil {
+		log.Printf("Error queueing summary: %v", err)
 		return err
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("telegram API error: status code %d", resp.StatusCode)
+	if remaining := w.FlushPending(ctx); remaining > 0 {
+		return fmt.Errorf("%d notifications still pending in the outbox", remaining)
 	}
 
-	log.Println("Summary sent to Telegram successfully")
+	log.Println("Summary sent successfully")
 	return nil
 }